@@ -0,0 +1,57 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+// Page backgrounds are painted by gofpdf's header function, which runs at
+// the start of every new page. The first page already exists when
+// rendering begins, so it is painted directly. Setting a background
+// therefore replaces any header function set on Pdf.
+
+// startBackground loads the background image, if any, paints the first
+// page and arranges for the rest to be painted.
+func (r *PdfRenderer) startBackground() {
+	r.background = nil
+	if dest := r.BackgroundImage; dest != "" {
+		img, err := r.loadImage(dest)
+		if err != nil {
+			r.tracer("Background (image error)", err.Error())
+			warning := ImageWarning{Destination: dest, Err: err}
+			r.imageWarnings = append(r.imageWarnings, warning)
+			if r.MissingImage == ImageFatal {
+				r.err = warning
+			}
+		} else {
+			r.background = img
+		}
+	}
+
+	if r.Background == nil && r.background == nil {
+		return
+	}
+	r.tracer("Background", r.BackgroundImage)
+	r.paintBackground()
+	r.Pdf.SetHeaderFunc(r.paintBackground)
+}
+
+// paintBackground covers the current page with the background colour and
+// then the background image.
+func (r *PdfRenderer) paintBackground() {
+	x, y := r.Pdf.GetXY()
+	w, h := r.Pdf.GetPageSize()
+	if c := r.Background; c != nil {
+		r.Pdf.SetFillColor(c.Red, c.Green, c.Blue)
+		r.Pdf.Rect(0, 0, w, h, "F")
+	}
+	if r.background != nil {
+		r.background.draw(r, 0, 0, w, h)
+	}
+	r.Pdf.SetXY(x, y)
+	r.setStyler(r.cs.peek().textStyle)
+}
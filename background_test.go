@@ -0,0 +1,52 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestBackground(t *testing.T) {
+	md := []byte("Page one.\n\n\\pagebreak\n\nPage two.\n\n\\newpage\n\nPage three.\n")
+
+	r := NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	r.Background = &Color{0x0d, 0x11, 0x17}
+
+	var buf bytes.Buffer
+	if err := r.Process(md).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.PageNo() != 3 {
+		t.Fatalf("got %d pages", r.Pdf.PageNo())
+	}
+	w, h := r.Pdf.GetPageSize()
+	rect := []byte(fmt.Sprintf("0.00 %.2f %.2f %.2f re f", h, w, -h))
+	if n := bytes.Count(buf.Bytes(), rect); n != 3 {
+		t.Errorf("got %d page backgrounds; want 3", n)
+	}
+}
+
+func TestBackgroundImage(t *testing.T) {
+	cases := []struct {
+		image string
+		warn  int
+	}{
+		{"image/fpdf.png", 0},
+		{"image/missing.png", 1},
+	}
+	for _, c := range cases {
+		r := NewPdfRenderer("", "", "")
+		r.BackgroundImage = c.image
+		if err := r.Process([]byte("Some text.\n")).Output(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.ImageWarnings()) != c.warn {
+			t.Errorf("%s: got warnings %v", c.image, r.ImageWarnings())
+		}
+		if (r.background != nil) != (c.warn == 0) {
+			t.Errorf("%s: got background %v", c.image, r.background)
+		}
+	}
+}
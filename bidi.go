@@ -0,0 +1,641 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Text in right-to-left scripts such as Hebrew and Arabic is stored in
+// logical order, the order in which it is read, but gofpdf writes the
+// characters of a string from left to right. So before text is written its
+// characters are put into visual order by the Unicode bidirectional
+// algorithm (UAX #9). This is a simplified form of it: the directional
+// formatting characters (embeddings, overrides and isolates) are ignored,
+// so each paragraph has a single embedding level, and the bidirectional
+// class of a character is derived from its script and general category
+// rather than from the full Unicode table. The numbers, brackets and
+// neutral characters between runs of each direction are resolved as the
+// algorithm says.
+
+// Direction values for PdfRenderer.Direction.
+const (
+	DirectionLTR  = "ltr"
+	DirectionRTL  = "rtl"
+	DirectionAuto = "auto"
+)
+
+// bidiClass is the bidirectional character type of a character.
+type bidiClass uint8
+
+const (
+	bidiL   bidiClass = iota // left-to-right
+	bidiR                    // right-to-left
+	bidiAL                   // Arabic letter
+	bidiEN                   // European number
+	bidiES                   // European separator
+	bidiET                   // European terminator
+	bidiAN                   // Arabic number
+	bidiCS                   // common separator
+	bidiNSM                  // non-spacing mark
+	bidiBN                   // boundary neutral
+	bidiB                    // paragraph separator
+	bidiS                    // segment separator
+	bidiWS                   // whitespace
+	bidiON                   // other neutral
+)
+
+// rtlRanges are the blocks of right-to-left scripts. Arabic letters are
+// those of the Arabic, Syriac and Thaana blocks and the Arabic
+// presentation forms; the rest are Hebrew and its like.
+var rtlRanges = []struct {
+	lo, hi rune
+	class  bidiClass
+}{
+	{0x0590, 0x05ff, bidiR},
+	{0x0600, 0x07bf, bidiAL},
+	{0x07c0, 0x085f, bidiR},
+	{0x0860, 0x08ff, bidiAL},
+	{0xfb1d, 0xfb4f, bidiR},
+	{0xfb50, 0xfdff, bidiAL},
+	{0xfe70, 0xfefe, bidiAL},
+	{0x10800, 0x10fff, bidiR},
+	{0x1e800, 0x1efff, bidiR},
+}
+
+// classOf gives the bidirectional class of a character.
+func classOf(ch rune) bidiClass {
+	switch ch {
+	case '\n', '\r', 0x1c, 0x1d, 0x1e, 0x85, 0x2029:
+		return bidiB
+	case '\t', 0x0b, 0x1f:
+		return bidiS
+	case ' ', 0x0c, 0x2028:
+		return bidiWS
+	case '+', '-', 0x207a, 0x207b, 0x208a, 0x208b, 0x2212, 0xfb29, 0xfe62, 0xfe63, 0xff0b, 0xff0d:
+		return bidiES
+	case '#', '$', '%', 0xb0, 0xb1, 0x0609, 0x060a, 0x066a, 0x2030, 0x2031, 0x2032, 0x2033, 0x2034, 0x2052:
+		return bidiET
+	case ',', '.', '/', ':', 0xa0, 0x060c, 0x202f, 0x2044, 0xfe50, 0xfe52, 0xfe55, 0xff0c, 0xff0e, 0xff0f, 0xff1a:
+		return bidiCS
+	case 0x0660, 0x0661, 0x0662, 0x0663, 0x0664, 0x0665, 0x0666, 0x0667, 0x0668, 0x0669, 0x066b, 0x066c, 0x06dd:
+		return bidiAN
+	case 0x200e: // left-to-right mark
+		return bidiL
+	case 0x200f: // right-to-left mark
+		return bidiR
+	case 0x061c: // Arabic letter mark
+		return bidiAL
+	}
+	switch {
+	case ch >= '0' && ch <= '9', ch >= 0x06f0 && ch <= 0x06f9, ch >= 0x2070 && ch <= 0x2079,
+		ch >= 0x2080 && ch <= 0x2089, ch >= 0xff10 && ch <= 0xff19, ch == 0xb2, ch == 0xb3, ch == 0xb9:
+		return bidiEN
+	case ch >= 0x0600 && ch <= 0x0605:
+		return bidiAN
+	case unicode.Is(unicode.Sc, ch):
+		return bidiET
+	case unicode.In(ch, unicode.Mn, unicode.Me):
+		return bidiNSM
+	case unicode.Is(unicode.Cf, ch) || unicode.IsControl(ch):
+		return bidiBN
+	case unicode.Is(unicode.Zs, ch):
+		return bidiWS
+	}
+	for _, rng := range rtlRanges {
+		if ch >= rng.lo && ch <= rng.hi {
+			return rng.class
+		}
+	}
+	if unicode.In(ch, unicode.L, unicode.Mc, unicode.Nd, unicode.Nl, unicode.No) {
+		return bidiL
+	}
+	return bidiON
+}
+
+func isRTLClass(c bidiClass) bool {
+	return c == bidiR || c == bidiAL
+}
+
+// hasRTL reports whether text contains any right-to-left characters, and
+// so needs reordering.
+func hasRTL(text string) bool {
+	for _, ch := range text {
+		if ch >= 0x0590 && isRTLClass(classOf(ch)) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseLevel finds the direction of a paragraph from its first strong
+// character, giving 1 if it is right-to-left and 0 otherwise.
+func baseLevel(text string) uint8 {
+	for _, ch := range text {
+		switch c := classOf(ch); {
+		case c == bidiL:
+			return 0
+		case isRTLClass(c):
+			return 1
+		}
+	}
+	return 0
+}
+
+// bidiLevels resolves the embedding level of each character of a
+// paragraph at the given level, so that odd levels are right-to-left.
+func bidiLevels(text []rune, para uint8) []uint8 {
+	n := len(text)
+	levels := make([]uint8, n)
+	if n == 0 {
+		return levels
+	}
+	orig := make([]bidiClass, n)
+	for i, ch := range text {
+		orig[i] = classOf(ch)
+	}
+	classes := append([]bidiClass(nil), orig...)
+
+	e := bidiL // the embedding direction, which is also that of sos and eos
+	if para%2 == 1 {
+		e = bidiR
+	}
+
+	// X9: boundary neutrals are ignored; here they take the class of
+	// what precedes them
+	prev := e
+	for i, c := range classes {
+		if c == bidiBN {
+			classes[i] = prev
+		}
+		prev = classes[i]
+	}
+
+	// W1: a non-spacing mark takes the class of what precedes it
+	prev = e
+	for i, c := range classes {
+		if c == bidiNSM {
+			classes[i] = prev
+		}
+		prev = classes[i]
+	}
+
+	// W2, W3: numbers after Arabic letters are Arabic numbers, and Arabic
+	// letters are then just right-to-left
+	strong := e
+	for i, c := range classes {
+		switch c {
+		case bidiL, bidiR, bidiAL:
+			strong = c
+		case bidiEN:
+			if strong == bidiAL {
+				classes[i] = bidiAN
+			}
+		}
+	}
+	for i, c := range classes {
+		if c == bidiAL {
+			classes[i] = bidiR
+		}
+	}
+
+	// W4: a single separator between two numbers of the same kind joins
+	// them
+	for i := 1; i < n-1; i++ {
+		before, after := classes[i-1], classes[i+1]
+		switch classes[i] {
+		case bidiES:
+			if before == bidiEN && after == bidiEN {
+				classes[i] = bidiEN
+			}
+		case bidiCS:
+			if before == after && (before == bidiEN || before == bidiAN) {
+				classes[i] = before
+			}
+		}
+	}
+
+	// W5: terminators next to European numbers are part of them
+	for i := 0; i < n; {
+		if classes[i] != bidiET {
+			i++
+			continue
+		}
+		j := i
+		for j < n && classes[j] == bidiET {
+			j++
+		}
+		if (i > 0 && classes[i-1] == bidiEN) || (j < n && classes[j] == bidiEN) {
+			for k := i; k < j; k++ {
+				classes[k] = bidiEN
+			}
+		}
+		i = j
+	}
+
+	// W6, W7: other separators and terminators are neutral, and European
+	// numbers in left-to-right text are left-to-right
+	strong = e
+	for i, c := range classes {
+		switch c {
+		case bidiES, bidiET, bidiCS:
+			classes[i] = bidiON
+		case bidiL, bidiR:
+			strong = c
+		case bidiEN:
+			if strong == bidiL {
+				classes[i] = bidiL
+			}
+		}
+	}
+
+	resolveBrackets(text, classes, orig, e)
+
+	// N1, N2: neutrals between characters of the same direction take that
+	// direction, and others take the embedding direction; numbers count
+	// as right-to-left
+	dir := func(c bidiClass) bidiClass {
+		if c == bidiEN || c == bidiAN {
+			return bidiR
+		}
+		return c
+	}
+	for i := 0; i < n; {
+		if !isNeutral(classes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < n && isNeutral(classes[j]) {
+			j++
+		}
+		before, after := e, e
+		if i > 0 {
+			before = dir(classes[i-1])
+		}
+		if j < n {
+			after = dir(classes[j])
+		}
+		resolved := e
+		if before == after {
+			resolved = before
+		}
+		for k := i; k < j; k++ {
+			classes[k] = resolved
+		}
+		i = j
+	}
+
+	// I1, I2: the implicit levels
+	for i, c := range classes {
+		level := para
+		if para%2 == 0 {
+			switch c {
+			case bidiR:
+				level++
+			case bidiEN, bidiAN:
+				level += 2
+			}
+		} else if c == bidiL || c == bidiEN || c == bidiAN {
+			level++
+		}
+		levels[i] = level
+	}
+
+	// L1: separators, and whitespace before them or at the end, are at the
+	// paragraph level
+	trailing := true
+	for i := n - 1; i >= 0; i-- {
+		switch orig[i] {
+		case bidiB, bidiS:
+			levels[i] = para
+			trailing = true
+		case bidiWS, bidiBN:
+			if trailing {
+				levels[i] = para
+			}
+		default:
+			trailing = false
+		}
+	}
+	return levels
+}
+
+func isNeutral(c bidiClass) bool {
+	return c == bidiON || c == bidiWS || c == bidiS || c == bidiB
+}
+
+// bracketPairs maps each opening bracket to its closing one.
+var bracketPairs = map[rune]rune{
+	'(': ')', '[': ']', '{': '}', 0x2045: 0x2046, 0x207d: 0x207e, 0x208d: 0x208e,
+	0x2329: 0x232a, 0x27e6: 0x27e7, 0x27e8: 0x27e9, 0x3008: 0x3009, 0x300a: 0x300b,
+	0xff08: 0xff09, 0xff3b: 0xff3d, 0xff5b: 0xff5d,
+}
+
+// resolveBrackets applies rule N0, so that a pair of brackets takes the
+// direction of the text it encloses, or failing that of its context.
+func resolveBrackets(text []rune, classes, orig []bidiClass, e bidiClass) {
+	type pair struct{ open, close int }
+	var pairs []pair
+	type opening struct {
+		closer rune
+		at     int
+	}
+	var stack []opening
+	for i, ch := range text {
+		if classes[i] != bidiON {
+			continue
+		}
+		if closer, ok := bracketPairs[ch]; ok {
+			if len(stack) == 63 {
+				break
+			}
+			stack = append(stack, opening{closer, i})
+			continue
+		}
+		for j := len(stack) - 1; j >= 0; j-- {
+			if stack[j].closer == ch {
+				pairs = append(pairs, pair{stack[j].at, i})
+				stack = stack[:j]
+				break
+			}
+		}
+	}
+	// the pairs are resolved in the order of their opening brackets
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && pairs[j].open < pairs[j-1].open; j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+
+	strongOf := func(c bidiClass) bidiClass {
+		switch c {
+		case bidiL:
+			return bidiL
+		case bidiR, bidiEN, bidiAN:
+			return bidiR
+		}
+		return bidiON
+	}
+	for _, p := range pairs {
+		inside := bidiON
+		for k := p.open + 1; k < p.close; k++ {
+			if s := strongOf(classes[k]); s == e {
+				inside = e
+				break
+			} else if s != bidiON {
+				inside = s
+			}
+		}
+		if inside == bidiON {
+			continue
+		}
+		if inside != e {
+			context := e
+			for k := p.open - 1; k >= 0; k-- {
+				if s := strongOf(classes[k]); s != bidiON {
+					context = s
+					break
+				}
+			}
+			if context != inside {
+				inside = e
+			}
+		}
+		for _, k := range []int{p.open, p.close} {
+			classes[k] = inside
+			// marks on a bracket follow it
+			for m := k + 1; m < len(classes) && orig[m] == bidiNSM; m++ {
+				classes[m] = inside
+			}
+		}
+	}
+}
+
+// reorderLevels gives the visual order of items at the given levels, as
+// indexes into them, by rule L2: from the highest level down to the lowest
+// odd one, every sequence at that level or above is reversed.
+func reorderLevels(levels []uint8) []int {
+	order := make([]int, len(levels))
+	for i := range order {
+		order[i] = i
+	}
+	highest, lowestOdd := uint8(0), uint8(255)
+	for _, l := range levels {
+		if l > highest {
+			highest = l
+		}
+		if l%2 == 1 && l < lowestOdd {
+			lowestOdd = l
+		}
+	}
+	for level := highest; level >= lowestOdd && level > 0; level-- {
+		for i := 0; i < len(levels); {
+			if levels[order[i]] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(levels) && levels[order[j]] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
+
+// mirrors are the characters whose glyphs are mirrored in right-to-left
+// text, rule L4.
+var mirrors = map[rune]rune{
+	'(': ')', ')': '(', '<': '>', '>': '<', '[': ']', ']': '[', '{': '}', '}': '{',
+	0xab: 0xbb, 0xbb: 0xab, 0x2039: 0x203a, 0x203a: 0x2039, 0x2045: 0x2046, 0x2046: 0x2045,
+	0x207d: 0x207e, 0x207e: 0x207d, 0x208d: 0x208e, 0x208e: 0x208d,
+	0x2264: 0x2265, 0x2265: 0x2264, 0x2329: 0x232a, 0x232a: 0x2329,
+	0x27e6: 0x27e7, 0x27e7: 0x27e6, 0x27e8: 0x27e9, 0x27e9: 0x27e8,
+	0x3008: 0x3009, 0x3009: 0x3008, 0x300a: 0x300b, 0x300b: 0x300a,
+	0xff08: 0xff09, 0xff09: 0xff08, 0xff3b: 0xff3d, 0xff3d: 0xff3b, 0xff5b: 0xff5d, 0xff5d: 0xff5b,
+}
+
+// reverseText reverses text written right-to-left, mirroring brackets and
+// the like and keeping combining marks after the characters they modify.
+func reverseText(text string) string {
+	runes := []rune(text)
+	out := make([]rune, 0, len(runes))
+	for end := len(runes); end > 0; {
+		start := end - 1
+		for start > 0 && unicode.In(runes[start], unicode.Mn, unicode.Me) {
+			start--
+		}
+		for i := start; i < end; i++ {
+			ch := runes[i]
+			if m, ok := mirrors[ch]; ok {
+				ch = m
+			}
+			out = append(out, ch)
+		}
+		end = start
+	}
+	return string(out)
+}
+
+// visualOrder reorders a line of text for writing, at the given paragraph
+// level.
+func visualOrder(text string, para uint8) string {
+	runes := []rune(text)
+	levels := bidiLevels(runes, para)
+
+	// reorder runs of characters at the same level, reversing the odd ones
+	type run struct {
+		text  string
+		level uint8
+	}
+	var runs []run
+	var runLevels []uint8
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && levels[j] == levels[i] {
+			j++
+		}
+		runs = append(runs, run{string(runes[i:j]), levels[i]})
+		runLevels = append(runLevels, levels[i])
+		i = j
+	}
+
+	var sb strings.Builder
+	for _, i := range reorderLevels(runLevels) {
+		if runs[i].level%2 == 1 {
+			sb.WriteString(reverseText(runs[i].text))
+		} else {
+			sb.WriteString(runs[i].text)
+		}
+	}
+	return sb.String()
+}
+
+// visual puts text written directly, rather than laid out, into visual
+// order, line by line. Text without right-to-left characters is unchanged.
+// Lines that gofpdf wraps are reordered as a whole, so this suits text
+// that fits on a line, such as a table cell.
+func (r *PdfRenderer) visual(text string) string {
+	return visualLines(text, r.paragraphLevel)
+}
+
+// visualLines reorders each line of text at the level given for it.
+func visualLines(text string, level func(string) uint8) string {
+	if !hasRTL(text) {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = visualOrder(line, level(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leftToRight is the level of code, which reads left-to-right in any
+// document.
+func leftToRight(string) uint8 {
+	return 0
+}
+
+// rtlLanguages are the languages written right-to-left, which set the
+// direction of a document that doesn't give one.
+var rtlLanguages = map[string]bool{
+	"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true, "iw": true,
+	"ps": true, "sd": true, "ug": true, "ur": true, "yi": true,
+}
+
+// direction gives the document direction: Direction if it is set, or else
+// that of the document language.
+func (r *PdfRenderer) direction() string {
+	switch d := strings.ToLower(r.Direction); d {
+	case DirectionLTR, DirectionRTL, DirectionAuto:
+		return d
+	}
+	lang := strings.ToLower(r.Language)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if rtlLanguages[lang] {
+		return DirectionRTL
+	}
+	return DirectionLTR
+}
+
+// paragraphLevel gives the embedding level of a paragraph according to
+// the document direction.
+func (r *PdfRenderer) paragraphLevel(text string) uint8 {
+	switch r.direction() {
+	case DirectionRTL:
+		return 1
+	case DirectionAuto:
+		return baseLevel(text)
+	}
+	return 0
+}
+
+// rtlBlock reports whether a block, such as a list, is right-to-left: in
+// an auto document, if its text starts with a right-to-left character.
+func (r *PdfRenderer) rtlBlock(text string) bool {
+	return r.paragraphLevel(text) == 1
+}
+
+// bidiRuns resolves the embedding levels of the text of a laid out block,
+// splitting its runs where the level changes. Inline images count as
+// neutral characters. It also gives the paragraph level. Runs are given
+// back unchanged for a left-to-right paragraph with no right-to-left text.
+func (r *PdfRenderer) bidiRuns(runs []textRun) ([]textRun, uint8) {
+	var sb strings.Builder
+	for _, run := range runs {
+		if run.img != nil {
+			sb.WriteRune(0xfffc) // object replacement character
+		} else {
+			sb.WriteString(run.text)
+		}
+	}
+	text := sb.String()
+	para := r.paragraphLevel(text)
+	if para == 0 && !hasRTL(text) {
+		return runs, 0
+	}
+
+	levels := bidiLevels([]rune(text), para)
+	out := make([]textRun, 0, len(runs))
+	at := 0
+	for _, run := range runs {
+		switch {
+		case run.hardBreak:
+			out = append(out, run)
+		case run.img != nil:
+			run.level = levels[at]
+			at++
+			out = append(out, run)
+		default:
+			chars := []rune(run.text)
+			start := 0
+			for i := 1; i <= len(chars); i++ {
+				if i == len(chars) || levels[at+i] != levels[at+start] {
+					piece := run
+					piece.text = string(chars[start:i])
+					piece.level = levels[at+start]
+					out = append(out, piece)
+					start = i
+				}
+			}
+			at += len(chars)
+		}
+	}
+	return out, para
+}
@@ -0,0 +1,157 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestVisualOrder(t *testing.T) {
+	cases := []struct {
+		text  string
+		level uint8
+		want  string
+	}{
+		{"plain text", 0, "plain text"},
+		{"שלום", 0, "םולש"},
+		{"abc שלום עולם def", 0, "abc םלוע םולש def"},
+		{"שלום abc", 1, "abc םולש"},
+		{"שלום abc", 0, "םולש abc"},
+		// numbers keep their order and brackets are mirrored
+		{"שלום (abc) 123", 1, "123 (abc) םולש"},
+		{"מחיר 3.14 ש", 1, "ש 3.14 ריחמ"},
+		{"טווח 1-2", 1, "1-2 חווט"},
+		{"x<ש", 1, "ש>x"},
+		// Arabic-Indic digits, and European digits after Arabic letters
+		{"عدد ١٢٣", 1, "١٢٣ ددع"},
+		{"رقم 45", 1, "45 مقر"},
+		// a combining mark stays after its letter
+		{"שָׁלוֹם", 0, "םוֹלשָׁ"},
+	}
+	for _, c := range cases {
+		if got := visualOrder(c.text, c.level); got != c.want {
+			t.Errorf("visualOrder(%q, %d) = %q, want %q", c.text, c.level, got, c.want)
+		}
+	}
+}
+
+func TestReorderLevels(t *testing.T) {
+	cases := []struct {
+		levels []uint8
+		want   []int
+	}{
+		{[]uint8{0, 0, 0}, []int{0, 1, 2}},
+		{[]uint8{1, 1, 1}, []int{2, 1, 0}},
+		{[]uint8{0, 1, 1, 0}, []int{0, 2, 1, 3}},
+		{[]uint8{1, 2, 2, 1}, []int{3, 1, 2, 0}},
+	}
+	for _, c := range cases {
+		if got := reorderLevels(c.levels); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("reorderLevels(%v) = %v, want %v", c.levels, got, c.want)
+		}
+	}
+}
+
+func TestDirection(t *testing.T) {
+	cases := []struct {
+		lang, dir, want string
+	}{
+		{"", "", DirectionLTR},
+		{"en", "", DirectionLTR},
+		{"he", "", DirectionRTL},
+		{"ar-EG", "", DirectionRTL},
+		{"he", "ltr", DirectionLTR},
+		{"en", "RTL", DirectionRTL},
+		{"", "auto", DirectionAuto},
+	}
+	for _, c := range cases {
+		r := NewPdfRenderer("", "", "")
+		r.Language, r.Direction = c.lang, c.dir
+		if got := r.direction(); got != c.want {
+			t.Errorf("lang %q dir %q: got %q, want %q", c.lang, c.dir, got, c.want)
+		}
+	}
+
+	r := NewPdfRenderer("", "", "")
+	r.Direction = DirectionAuto
+	if r.paragraphLevel("123 שלום world") != 1 || r.paragraphLevel("hello שלום") != 0 {
+		t.Errorf("auto direction should follow the first strong character")
+	}
+}
+
+func TestDirectionFrontMatter(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Process([]byte("---\ndir: rtl\n---\n\ntext\n"))
+	if r.Direction != DirectionRTL {
+		t.Errorf("got %q", r.Direction)
+	}
+}
+
+// utf16Text gives text as a UTF-8 font writes it in a PDF.
+func utf16Text(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+func dejaVuRenderer(t *testing.T) *PdfRenderer {
+	needDejaVu(t)
+	r := NewPdfRenderer("", "", dejaVuDir)
+	if err := r.RegisterFontFamily("DejaVu", "DejaVuSans.ttf", "DejaVuSans-Bold.ttf", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	r.SetFonts("DejaVu", "DejaVu", "")
+	return r
+}
+
+func TestRightToLeftParagraph(t *testing.T) {
+	r := dejaVuRenderer(t)
+	r.Pdf.SetCompression(false)
+	var buf bytes.Buffer
+	err := r.Process([]byte("---\ndir: rtl\n---\n\nשלום עולם\n\n* פריט\n")).Output(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	for _, word := range []string{"םולש", "םלוע", "טירפ"} {
+		if !bytes.Contains(pdf, utf16Text(word)) {
+			t.Errorf("%s was not written reversed", word)
+		}
+	}
+	if bytes.Contains(pdf, utf16Text("שלום")) {
+		t.Errorf("text was written in logical order")
+	}
+}
+
+func TestRightToLeftLayout(t *testing.T) {
+	r := dejaVuRenderer(t)
+	r.Direction = DirectionRTL
+	r.cs.stack[0].textStyle = r.Normal
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+
+	r.startLayout(r.Normal, true)
+	r.addText(r.Normal, "שלום", "")
+	r.flushLayout()
+
+	// the line ends at the right margin
+	r.setStyler(r.Normal)
+	x := r.Pdf.GetX()
+	if want := pageW - rm; x < want-0.01 || x > want+0.01 {
+		t.Errorf("line ends at %.2f, want %.2f (left margin %.2f)", x, want, lm)
+	}
+}
+
+func TestRightToLeft(t *testing.T) {
+	needDejaVu(t)
+	testit("Right to left.md", t, func(r *PdfRenderer) {
+		r.fontDir = dejaVuDir
+		if err := r.RegisterFontFamily("DejaVu", "DejaVuSans.ttf", "DejaVuSans-Bold.ttf", "", ""); err != nil {
+			t.Fatal(err)
+		}
+		r.SetFonts("DejaVu", "DejaVu", "")
+	})
+}
@@ -13,12 +13,39 @@ import (
 )
 
 var (
-	input, output string
+	input, output, baseDir string
+	lang, patterns         string
+	dir                    string
+	theme, saveTheme       string
+	stylesheet             string
+	fallbacks              string
+	emojiDir               string
+	title, author          string
+	titlePage              bool
+	noCache, strict        bool
+	captions               bool
+	pageBreakLevel         int
 )
 
 func main() {
 	flag.StringVar(&input, "i", "", "Input text filename; default is os.Stdin")
 	flag.StringVar(&output, "o", "", "Output PDF filename; required")
+	flag.StringVar(&baseDir, "b", "", "Base directory for relative image paths; default is the input file's directory")
+	flag.BoolVar(&noCache, "no-cache", false, "Do not cache images fetched from http(s) URLs")
+	flag.BoolVar(&strict, "strict", false, "Fail if any image cannot be loaded")
+	flag.BoolVar(&captions, "captions", false, "Caption images with their title or alt text")
+	flag.StringVar(&lang, "lang", "", "Document language, e.g. en or de, used for hyphenation")
+	flag.StringVar(&dir, "dir", "", "Text direction: ltr, rtl or auto; default is that of the -lang")
+	flag.StringVar(&patterns, "hyphenation", "", "File of TeX hyphenation patterns for the document language")
+	flag.IntVar(&pageBreakLevel, "page-break-level", 0, "Start headings of this level and above on a new page, e.g. 1 for H1")
+	flag.StringVar(&title, "title", "", "Document title")
+	flag.StringVar(&author, "author", "", "Document author")
+	flag.BoolVar(&titlePage, "title-page", false, "Add a title page; needs a -title")
+	flag.StringVar(&theme, "theme", "", "Theme: one of "+strings.Join(mdtopdf.BuiltinThemes(), ", ")+", or a JSON or YAML theme file")
+	flag.StringVar(&stylesheet, "css", "", "CSS stylesheet, applied after the theme")
+	flag.StringVar(&fallbacks, "fallback-fonts", "", "Comma-separated TrueType font files to use for characters the main fonts lack")
+	flag.StringVar(&emojiDir, "emoji-dir", "", "Directory of emoji images named by code point, e.g. 1f680.png, as in Twemoji")
+	flag.StringVar(&saveTheme, "save-theme", "", "Write the theme in use to a JSON or YAML file, to use as a starting point")
 	var help = flag.Bool("help", false, "Show usage message")
 
 	flag.Parse()
@@ -52,24 +79,136 @@ func main() {
 	pf := mdtopdf.NewPdfRenderer("", "", fontDir)
 	pf.TracerFile = "trace.log"
 
+	if baseDir == "" && input != "" {
+		baseDir = filepath.Dir(input)
+	}
+	pf.BaseDir = baseDir
+
+	// the default font comes first, so that the front matter, theme and
+	// stylesheet can replace it
 	if fileExists(fontDir + "/" + fontFile) {
 		fmt.Println(fontDir + "/" + fontFile)
-		pf.Pdf.AddUTF8Font(fontName, "", fontFile)
-		pf.Pdf.SetFont(fontName, "", 10)
-		if pf.Pdf.Err() {
-			fmt.Println(pf.Pdf.Error())
+		faces := [3]string{fontBold, fontItalic, fontBoldItalic}
+		for i, face := range faces {
+			if !fileExists(fontDir + "/" + face) {
+				faces[i] = ""
+			}
+		}
+		err = pf.RegisterFontFamily(fontName, fontFile, faces[0], faces[1], faces[2])
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
-		pf.Normal = mdtopdf.Styler{Font: fontName, Style: "", Size: 10, Spacing: 4, TextColor: mdtopdf.Black, FillColor: mdtopdf.White}
+		pf.SetFonts(fontName, "", "")
 	}
 
-	pf.Pdf.SetSubject("How to convert markdown to PDF", true)
-	pf.Pdf.SetTitle("Example PDF converted from Markdown", true)
+	pf.Process(content)
 
-	err = pf.Process(content).ToFile(output)
+	// the option overrides the front matter
+	if t, ok := mdtopdf.BuiltinTheme(theme); ok {
+		pf.SetTheme(t)
+	} else if theme != "" {
+		t, err := mdtopdf.LoadTheme(theme)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pf.SetTheme(t)
+	}
+	if stylesheet != "" {
+		css, err := ioutil.ReadFile(stylesheet)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = pf.SetStylesheet(string(css)); err != nil {
+			log.Fatalf("%s: %v", stylesheet, err)
+		}
+	}
+
+	if !noCache {
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			loader := mdtopdf.NewImageLoader()
+			loader.CacheDir = filepath.Join(cacheDir, "mdtopdf")
+			pf.ImageLoader = loader
+		}
+	}
+
+	if fallbacks != "" {
+		var families []string
+		for _, file := range strings.Split(fallbacks, ",") {
+			family := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			// absolute, as relative paths would be taken from fontDir
+			file, err := filepath.Abs(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err = pf.RegisterFontFamily(family, file, "", "", ""); err != nil {
+				log.Fatal(err)
+			}
+			families = append(families, family)
+		}
+		pf.SetFallbackFonts(families...)
+	}
+
+	if saveTheme != "" {
+		if err := pf.Theme().Save(saveTheme); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// options override the front matter
+	if title != "" {
+		pf.Metadata.Title = title
+	}
+	if author != "" {
+		pf.Metadata.Authors = []string{author}
+	}
+	if titlePage {
+		pf.TitlePage = true
+	}
+
+	if strict {
+		pf.MissingImage = mdtopdf.ImageFatal
+	}
+	pf.FigureCaptions = captions
+
+	if emojiDir != "" {
+		pf.EmojiImages = os.DirFS(emojiDir)
+	}
+
+	if lang != "" {
+		pf.Language = lang
+	}
+	if dir != "" {
+		pf.Direction = dir
+	}
+	pf.PageBreakLevel = pageBreakLevel
+	if patterns != "" {
+		if pf.Language == "" {
+			usage("The -hyphenation option needs a -lang")
+		}
+		f, err := os.Open(patterns)
+		if err != nil {
+			log.Fatal(err)
+		}
+		h, err := mdtopdf.NewHyphenator(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", patterns, err)
+		}
+		mdtopdf.RegisterHyphenator(pf.Language, h)
+	}
+
+	err = pf.ToFile(output)
 	if err != nil {
 		log.Fatalf("pdf.ToFile() error:%v", err)
 	}
+
+	for _, w := range pf.StylesheetWarnings() {
+		log.Printf("warning: %v", w)
+	}
+	for _, w := range pf.ImageWarnings() {
+		log.Printf("warning: %v", w)
+	}
 }
 
 func usage(msg string) {
@@ -92,7 +231,10 @@ const (
 	//fontName = "LiberationSerif-Regular"
 	//fontFile = fontName + ".ttf"
 
-	fontDir  = "/usr/share/fonts"
-	fontName = "DejaVuSerif"
-	fontFile = "truetype/dejavu/" + fontName + ".ttf"
+	fontDir        = "/usr/share/fonts"
+	fontName       = "DejaVuSerif"
+	fontFile       = "truetype/dejavu/" + fontName + ".ttf"
+	fontBold       = "truetype/dejavu/" + fontName + "-Bold.ttf"
+	fontItalic     = "truetype/dejavu/" + fontName + "-Italic.ttf"
+	fontBoldItalic = "truetype/dejavu/" + fontName + "-BoldItalic.ttf"
 )
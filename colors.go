@@ -1,6 +1,11 @@
 package mdtopdf
 
-import "strconv"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 var (
 	Black = Color{0, 0, 0}
@@ -16,26 +21,215 @@ func Grey(level int) Color {
 	}
 }
 
-// ColorOf implements basic CSS-like colours such as "#0366d6".
+// ColorOf implements CSS colours such as "#0366d6", "teal" or
+// "rgb(0 128 128)"; see ParseColor. It gives Black for anything it can't
+// parse.
 func ColorOf(css string) Color {
-	if css == "" {
+	c, err := ParseColor(css)
+	if err != nil {
 		return Black
 	}
+	return c
+}
+
+// ParseColor parses a CSS colour: a named colour, "#rgb", "#rgba",
+// "#rrggbb", "#rrggbbaa", "rgb()", "rgba()", "hsl()" or "hsla()". As
+// colours are opaque, any alpha is ignored.
+func ParseColor(css string) (Color, error) {
+	c, _, err := parseColor(css)
+	return c, err
+}
+
+// parseColor parses a CSS colour, giving its alpha as well, from 0
+// (transparent) to 1 (opaque).
+func parseColor(css string) (Color, float64, error) {
+	v := strings.ToLower(strings.TrimSpace(css))
+	if c, ok := namedColors[v]; ok {
+		return c, 1, nil
+	}
+	if v == "transparent" {
+		return Black, 0, nil
+	}
+
+	if strings.HasPrefix(v, "#") {
+		if c, a, ok := hexColor(v[1:]); ok {
+			return c, a, nil
+		}
+		return Black, 1, fmt.Errorf("invalid colour %q", css)
+	}
+
+	open := strings.IndexByte(v, '(')
+	if open > 0 && strings.HasSuffix(v, ")") {
+		args, ok := colorArgs(v[open+1 : len(v)-1])
+		if ok {
+			switch v[:open] {
+			case "rgb", "rgba":
+				if c, a, ok := rgbColor(args); ok {
+					return c, a, nil
+				}
+			case "hsl", "hsla":
+				if c, a, ok := hslColor(args); ok {
+					return c, a, nil
+				}
+			}
+		}
+	}
+	return Black, 1, fmt.Errorf("invalid colour %q", css)
+}
 
-	if css[0] == '#' {
-		if len(css) == 4 {
-			r, _ := strconv.ParseInt(css[1:2], 16, 64)
-			g, _ := strconv.ParseInt(css[2:3], 16, 64)
-			b, _ := strconv.ParseInt(css[3:], 16, 64)
-			return Color{Red: int(r), Green: int(g), Blue: int(b)}
+// hexColor parses the digits of a hex colour, which have one digit per
+// component or two, with or without alpha.
+func hexColor(digits string) (Color, float64, bool) {
+	n, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return Black, 1, false
+	}
+	var rgba [4]int
+	switch len(digits) {
+	case 3, 4:
+		for i := range digits {
+			nibble := int(n>>(4*(len(digits)-1-i))) & 0xf
+			rgba[i] = nibble * 0x11
 		}
-		if len(css) == 7 {
-			r, _ := strconv.ParseInt(css[1:3], 16, 64)
-			g, _ := strconv.ParseInt(css[3:5], 16, 64)
-			b, _ := strconv.ParseInt(css[5:], 16, 64)
-			return Color{Red: int(r), Green: int(g), Blue: int(b)}
+	case 6, 8:
+		for i := 0; i < len(digits)/2; i++ {
+			rgba[i] = int(n>>(8*(len(digits)/2-1-i))) & 0xff
 		}
+	default:
+		return Black, 1, false
 	}
+	alpha := 1.0
+	if len(digits) == 4 || len(digits) == 8 {
+		alpha = float64(rgba[3]) / 255
+	}
+	return Color{rgba[0], rgba[1], rgba[2]}, alpha, true
+}
 
-	return Black
+// colorArgs splits the arguments of a colour function, written either
+// with commas, as in "rgb(1, 2, 3)", or with spaces and an optional alpha
+// after a slash, as in "rgb(1 2 3 / 50%)". There are always four; the
+// alpha is "1" if missing.
+func colorArgs(s string) ([]string, bool) {
+	var args []string
+	if strings.Contains(s, ",") {
+		for _, a := range strings.Split(s, ",") {
+			args = append(args, strings.TrimSpace(a))
+		}
+	} else {
+		parts := strings.SplitN(s, "/", 2)
+		args = strings.Fields(parts[0])
+		if len(parts) == 2 {
+			args = append(args, strings.TrimSpace(parts[1]))
+		}
+	}
+	if len(args) == 3 {
+		args = append(args, "1")
+	}
+	return args, len(args) == 4
+}
+
+// rgbColor converts the arguments of rgb(), which are numbers from 0 to
+// 255 or percentages.
+func rgbColor(args []string) (Color, float64, bool) {
+	var rgb [3]int
+	for i := 0; i < 3; i++ {
+		f, pct, ok := cssNumber(args[i])
+		if !ok {
+			return Black, 1, false
+		}
+		if pct {
+			f = f * 255 / 100
+		}
+		rgb[i] = int(math.Round(clamp(f, 0, 255)))
+	}
+	alpha, ok := alphaOf(args[3])
+	return Color{rgb[0], rgb[1], rgb[2]}, alpha, ok
+}
+
+// hslColor converts the arguments of hsl(): a hue in degrees (or another
+// angle unit) and the saturation and lightness as percentages.
+func hslColor(args []string) (Color, float64, bool) {
+	h, ok := cssAngle(args[0])
+	if !ok {
+		return Black, 1, false
+	}
+	s, _, ok1 := cssNumber(args[1])
+	l, _, ok2 := cssNumber(args[2])
+	if !ok1 || !ok2 {
+		return Black, 1, false
+	}
+	s, l = clamp(s, 0, 100)/100, clamp(l, 0, 100)/100
+
+	// as given in CSS Color Module Level 4
+	f := func(n float64) int {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		v := l - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))
+		return int(math.Round(v * 255))
+	}
+	alpha, ok := alphaOf(args[3])
+	return Color{f(0), f(8), f(4)}, alpha, ok
+}
+
+// cssNumber parses a number or percentage.
+func cssNumber(s string) (float64, bool, bool) {
+	pct := strings.HasSuffix(s, "%")
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return f, pct, err == nil
+}
+
+// cssAngle parses an angle, in degrees unless it has a unit, and brings it
+// into the range 0 to 360.
+func cssAngle(s string) (float64, bool) {
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"deg", 1}, {"grad", 0.9}, {"rad", 180 / math.Pi}, {"turn", 360},
+	}
+	scale := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, scale = strings.TrimSuffix(s, u.suffix), u.scale
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	f = math.Mod(f*scale, 360)
+	if f < 0 {
+		f += 360
+	}
+	return f, true
+}
+
+// alphaOf parses an alpha value, a number from 0 to 1 or a percentage.
+func alphaOf(s string) (float64, bool) {
+	f, pct, ok := cssNumber(s)
+	if pct {
+		f /= 100
+	}
+	return clamp(f, 0, 1), ok
+}
+
+func clamp(f, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, f))
+}
+
+// MarshalText gives the colour in CSS hex form, e.g. "#0366d6".
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("#%02x%02x%02x", c.Red, c.Green, c.Blue)), nil
+}
+
+// UnmarshalText reads a colour in any of the forms that ParseColor
+// accepts.
+func (c *Color) UnmarshalText(text []byte) error {
+	parsed, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
 }
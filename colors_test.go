@@ -0,0 +1,67 @@
+package mdtopdf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	cases := []struct {
+		in    string
+		want  Color
+		alpha float64
+	}{
+		{"#fff", Color{255, 255, 255}, 1},
+		{"#1a2", Color{0x11, 0xaa, 0x22}, 1},
+		{"#1a28", Color{0x11, 0xaa, 0x22}, 0x88 / 255.0},
+		{"#0366D6", Color{3, 102, 214}, 1},
+		{"#0366d680", Color{3, 102, 214}, 128 / 255.0},
+		{"teal", Color{0, 128, 128}, 1},
+		{" RebeccaPurple ", Color{0x66, 0x33, 0x99}, 1},
+		{"transparent", Color{0, 0, 0}, 0},
+		{"rgb(10, 20, 30)", Color{10, 20, 30}, 1},
+		{"rgb(10 20 30)", Color{10, 20, 30}, 1},
+		{"rgb(100%, 0%, 50%)", Color{255, 0, 128}, 1},
+		{"rgb(300, -5, 0)", Color{255, 0, 0}, 1},
+		{"rgba(10, 20, 30, 0.25)", Color{10, 20, 30}, 0.25},
+		{"rgb(10 20 30 / 40%)", Color{10, 20, 30}, 0.4},
+		{"hsl(0, 100%, 50%)", Color{255, 0, 0}, 1},
+		{"hsl(120, 100%, 25%)", Color{0, 128, 0}, 1},
+		{"hsl(240deg 100% 50%)", Color{0, 0, 255}, 1},
+		{"hsl(0.5turn, 100%, 50%)", Color{0, 255, 255}, 1},
+		{"hsl(-60, 100%, 50%)", Color{255, 0, 255}, 1},
+		{"hsl(0, 0%, 100%)", Color{255, 255, 255}, 1},
+		{"hsla(30, 100%, 50%, 0.5)", Color{255, 128, 0}, 0.5},
+	}
+	for _, c := range cases {
+		got, alpha, err := parseColor(c.in)
+		if err != nil {
+			t.Errorf("%q: %v", c.in, err)
+		} else if got != c.want || math.Abs(alpha-c.alpha) > 1e-9 {
+			t.Errorf("%q: got %v %v; want %v %v", c.in, got, alpha, c.want, c.alpha)
+		}
+	}
+
+	for _, bad := range []string{"", "#", "#12", "#12345", "#ggg", "bluish", "rgb(1, 2)", "rgb(1, 2, x)", "hsl(red, 1%, 2%)", "cmyk(1, 2, 3, 4)"} {
+		if _, err := ParseColor(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+		if c := ColorOf(bad); c != Black {
+			t.Errorf("%q: got %v; want black", bad, c)
+		}
+	}
+}
+
+func TestColorText(t *testing.T) {
+	var c Color
+	if err := c.UnmarshalText([]byte("hsl(210, 50%, 40%)")); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := c.MarshalText()
+	if string(text) != "#336699" {
+		t.Errorf("got %s", text)
+	}
+	if err := c.UnmarshalText([]byte("#12345")); err == nil {
+		t.Error("expected an error")
+	}
+}
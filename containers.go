@@ -58,6 +58,7 @@ type containerState struct {
 	textStyle      Styler
 	leftMargin     float64
 	firstParagraph bool
+	rtl            bool // a right-to-left list or block quote
 
 	// populated if node type is a list
 	listkind   listType
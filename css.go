@@ -0,0 +1,560 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A stylesheet is a restricted form of CSS. Only element selectors are
+// understood, for these elements:
+//
+//	h1 … h6     the heading stylers
+//	p           Normal
+//	code, pre   Backtick
+//	blockquote  Blockquote; its left margin and padding set QuoteIndent
+//	a           Link
+//	table       THeader and TBody; its border sets BorderColor
+//	th, td      THeader, TBody; their vertical padding sets the Spacing
+//	li          its left margin and padding set the indent
+//	@page       its margins set the page margins
+//
+// The properties are font-family, font-size, font-weight, font-style,
+// color, background-color, margin, padding, line-height, text-align and
+// border. The fonts of a font-family list after the first that can be used
+// become the styler's FallbackFonts. The top and bottom margin and padding
+// of p, h1 … h6, pre and blockquote widen the Spacing, there being no
+// other gap between blocks. As in a browser, rules and declarations that
+// aren't understood are skipped, but margins, paddings and borders that
+// can't be applied are reported by StylesheetWarnings.
+
+// cssRule is a rule of a stylesheet: its selectors, and the property and
+// value of each declaration.
+type cssRule struct {
+	selectors []string
+	decls     [][2]string
+}
+
+// parseCSS splits a stylesheet into its rules. At-rules other than @page,
+// such as @media, are skipped along with their contents.
+func parseCSS(css string) ([]cssRule, error) {
+	css = stripCSSComments(css)
+	var rules []cssRule
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			if strings.TrimSpace(css) != "" {
+				return nil, fmt.Errorf("stylesheet: expected '{' after %q", strings.TrimSpace(css))
+			}
+			return rules, nil
+		}
+		prelude := strings.TrimSpace(css[:open])
+		end := matchingBrace(css, open)
+		if end < 0 {
+			return nil, fmt.Errorf("stylesheet: no '}' closing %q", prelude)
+		}
+		body := css[open+1 : end]
+		css = css[end+1:]
+
+		if strings.HasPrefix(prelude, "@") && !strings.EqualFold(prelude, "@page") {
+			continue
+		}
+		rule := cssRule{}
+		for _, sel := range strings.Split(prelude, ",") {
+			rule.selectors = append(rule.selectors, strings.ToLower(strings.TrimSpace(sel)))
+		}
+		for _, decl := range splitCSS(body, ';') {
+			colon := strings.IndexByte(decl, ':')
+			if colon < 0 {
+				continue
+			}
+			prop := strings.ToLower(strings.TrimSpace(decl[:colon]))
+			value := strings.TrimSpace(decl[colon+1:])
+			value = strings.TrimSpace(strings.TrimSuffix(value, "!important"))
+			if prop != "" && value != "" {
+				rule.decls = append(rule.decls, [2]string{prop, value})
+			}
+		}
+		rules = append(rules, rule)
+	}
+}
+
+func stripCSSComments(css string) string {
+	var buf strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			buf.WriteString(css)
+			return buf.String()
+		}
+		buf.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return buf.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
+
+// matchingBrace gives the index of the '}' closing the '{' at open, or -1.
+func matchingBrace(css string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitCSS splits text at sep, except within quotes or parentheses.
+func splitCSS(text string, sep byte) []string {
+	var parts []string
+	var quote byte
+	nesting, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			nesting++
+		case c == ')':
+			nesting--
+		case c == sep && nesting == 0:
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// SetStylesheet changes the renderer's appearance according to a CSS
+// stylesheet, starting from its current theme. Like SetTheme, it should be
+// called before the document is rendered.
+func (r *PdfRenderer) SetStylesheet(css string) error {
+	rules, err := parseCSS(css)
+	if err != nil {
+		return err
+	}
+
+	t := r.Theme()
+	sheet := stylesheet{r: r, t: &t,
+		lineHeights: make(map[*Styler]float64),
+		cellPadding: make(map[*Styler]*[2]float64)}
+	for _, rule := range rules {
+		for _, sel := range rule.selectors {
+			for _, decl := range rule.decls {
+				sheet.declare(sel, decl[0], decl[1])
+			}
+		}
+	}
+	for s, factor := range sheet.lineHeights {
+		s.Spacing = math.Max(0, (factor-1)*s.Size)
+	}
+	for s, p := range sheet.cellPadding {
+		s.Spacing = math.Max(0, p[0]+p[1])
+	}
+
+	r.SetTheme(t)
+	return nil
+}
+
+// LoadStylesheet reads a CSS file and calls SetStylesheet. A relative path
+// is taken from BaseDir.
+func (r *PdfRenderer) LoadStylesheet(path string) error {
+	if r.BaseDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(r.BaseDir, path)
+	}
+	css, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = r.SetStylesheet(string(css)); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// stylesheet applies declarations to a theme.
+type stylesheet struct {
+	r *PdfRenderer
+	t *Theme
+
+	// lineHeights holds unitless line heights, which are multiples of the
+	// font size and so are worked out once all the sizes are known
+	lineHeights map[*Styler]float64
+
+	// cellPadding holds the top and bottom paddings of table cells
+	cellPadding map[*Styler]*[2]float64
+
+	// quoteIndent and listIndent hold the left margin and padding of
+	// blockquote and li
+	quoteIndent, listIndent [2]float64
+}
+
+// StyleWarning describes a declaration of a stylesheet that could not be
+// applied.
+type StyleWarning struct {
+	Selector, Property, Value string
+}
+
+func (w StyleWarning) Error() string {
+	return fmt.Sprintf("stylesheet: %s { %s: %s } is not supported", w.Selector, w.Property, w.Value)
+}
+
+// StylesheetWarnings returns the declarations of margins, paddings and
+// borders that SetStylesheet could not apply.
+func (r *PdfRenderer) StylesheetWarnings() []StyleWarning {
+	return r.styleWarnings
+}
+
+// stylers gives the stylers that an element selector applies to.
+func (s *stylesheet) stylers(sel string) []*Styler {
+	t := s.t
+	switch sel {
+	case "p":
+		return []*Styler{&t.Normal}
+	case "h1":
+		return []*Styler{&t.H1}
+	case "h2":
+		return []*Styler{&t.H2}
+	case "h3":
+		return []*Styler{&t.H3}
+	case "h4":
+		return []*Styler{&t.H4}
+	case "h5":
+		return []*Styler{&t.H5}
+	case "h6":
+		return []*Styler{&t.H6}
+	case "code", "pre":
+		return []*Styler{&t.Backtick}
+	case "blockquote":
+		return []*Styler{&t.Blockquote}
+	case "a":
+		return []*Styler{&t.Link}
+	case "table":
+		return []*Styler{&t.THeader, &t.TBody}
+	case "th":
+		return []*Styler{&t.THeader}
+	case "td":
+		return []*Styler{&t.TBody}
+	}
+	return nil
+}
+
+func (s *stylesheet) declare(sel, prop, value string) {
+	switch {
+	case sel == "@page":
+		s.pageMargin(prop, value)
+		return
+	case strings.HasPrefix(prop, "margin") || strings.HasPrefix(prop, "padding"):
+		s.box(sel, prop, value)
+		return
+	case strings.HasPrefix(prop, "border"):
+		s.border(sel, prop, value)
+		return
+	}
+
+	for _, st := range s.stylers(sel) {
+		s.style(st, prop, value)
+	}
+}
+
+// style applies a declaration to a styler.
+func (s *stylesheet) style(st *Styler, prop, value string) {
+	switch prop {
+	case "font-family":
+		if font, fallbacks := s.r.fontFamily(value); font != "" {
+			st.Font, st.FallbackFonts = font, strings.Join(fallbacks, ", ")
+		}
+	case "font-size":
+		if size, ok := cssLength(value, s.t.Normal.Size); ok && size > 0 {
+			st.Size = size
+		}
+	case "font-weight":
+		switch strings.ToLower(value) {
+		case "bold", "bolder", "600", "700", "800", "900":
+			st.Style = setStyle(st.Style, "b", true)
+		case "normal", "lighter", "100", "200", "300", "400", "500":
+			st.Style = setStyle(st.Style, "b", false)
+		}
+	case "font-style":
+		switch strings.ToLower(value) {
+		case "italic", "oblique":
+			st.Style = setStyle(st.Style, "i", true)
+		case "normal":
+			st.Style = setStyle(st.Style, "i", false)
+		}
+	case "color":
+		if c, err := ParseColor(value); err == nil {
+			st.TextColor = c
+		}
+	case "background-color":
+		// there is no transparency, so the nearest is the usual white
+		if c, alpha, err := parseColor(value); err == nil && alpha == 0 {
+			st.FillColor = White
+		} else if err == nil {
+			st.FillColor = c
+		}
+	case "line-height":
+		if factor, err := strconv.ParseFloat(value, 64); err == nil {
+			s.lineHeights[st] = factor
+		} else if strings.HasSuffix(value, "%") {
+			if pc, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64); err == nil {
+				s.lineHeights[st] = pc / 100
+			}
+		} else if lh, ok := cssLength(value, st.Size); ok {
+			delete(s.lineHeights, st)
+			st.Spacing = math.Max(0, lh-st.Size)
+		}
+	case "text-align":
+		switch strings.ToLower(value) {
+		case "left", "start":
+			st.Align = AlignLeft
+		case "right", "end":
+			st.Align = AlignRight
+		case "center":
+			st.Align = AlignCenter
+		case "justify":
+			st.Align = AlignJustify
+		}
+	}
+}
+
+// box applies a margin or padding, side by side. A side that can't be
+// applied, unless it is zero, is reported.
+func (s *stylesheet) box(sel, prop, value string) {
+	kind, only := prop, ""
+	if i := strings.IndexByte(prop, '-'); i >= 0 {
+		kind, only = prop[:i], prop[i+1:]
+	}
+	unsupported := false
+	for i, side := range []string{"top", "right", "bottom", "left"} {
+		var v float64
+		var ok bool
+		switch only {
+		case "":
+			v, ok = cssBox(value, s.t.Normal.Size, i)
+		case side:
+			v, ok = cssLength(value, s.t.Normal.Size)
+		}
+		if ok && !s.boxSide(sel, kind, side, v) && v != 0 {
+			unsupported = true
+		}
+	}
+	if unsupported {
+		s.warn(sel, prop, value)
+	}
+}
+
+// boxSide applies one side of a margin or padding: the left side indents
+// block quotes and lists, and the top and bottom of table cells pad them.
+// The space above and below other blocks is set by the renderer, not the
+// stylesheet.
+func (s *stylesheet) boxSide(sel, kind, side string, v float64) bool {
+	i := 0
+	if kind == "padding" {
+		i = 1
+	}
+	vertical := side == "top" || side == "bottom"
+	switch {
+	case side == "left" && sel == "blockquote":
+		s.quoteIndent[i] = v
+		s.t.QuoteIndent = s.quoteIndent[0] + s.quoteIndent[1]
+	case side == "left" && sel == "li":
+		s.listIndent[i] = v
+		s.t.Indent = s.listIndent[0] + s.listIndent[1]
+	case vertical && (sel == "th" || sel == "td"):
+		if kind != "padding" {
+			return false
+		}
+		st := s.stylers(sel)[0]
+		if s.cellPadding[st] == nil {
+			s.cellPadding[st] = new([2]float64)
+		}
+		s.cellPadding[st][sideIndex(side)] = v
+	default:
+		return false
+	}
+	return true
+}
+
+// sideIndex is 0 for the top and 1 for the bottom.
+func sideIndex(side string) int {
+	if side == "bottom" {
+		return 1
+	}
+	return 0
+}
+
+// border sets BorderColor from the border of a table, the only border
+// that is drawn.
+func (s *stylesheet) border(sel, prop, value string) {
+	if (sel != "table" && sel != "th" && sel != "td") || (prop != "border" && prop != "border-color") {
+		s.warn(sel, prop, value)
+		return
+	}
+	for _, part := range splitCSS(value, ' ') {
+		if c, err := ParseColor(part); err == nil {
+			s.t.BorderColor = c
+		}
+	}
+}
+
+func (s *stylesheet) warn(sel, prop, value string) {
+	s.r.styleWarnings = append(s.r.styleWarnings, StyleWarning{Selector: sel, Property: prop, Value: value})
+}
+
+// pageMargin sets the page margins.
+func (s *stylesheet) pageMargin(prop, value string) {
+	m := &s.t.Margins
+	sides := map[string]*float64{"margin-top": &m.Top, "margin-right": &m.Right, "margin-bottom": &m.Bottom, "margin-left": &m.Left}
+	if prop == "margin" {
+		for i, side := range []*float64{&m.Top, &m.Right, &m.Bottom, &m.Left} {
+			if v, ok := cssBox(value, s.t.Normal.Size, i); ok {
+				*side = v
+			}
+		}
+	} else if side, found := sides[prop]; found {
+		if v, ok := cssLength(value, s.t.Normal.Size); ok {
+			*side = v
+		}
+	}
+}
+
+// cssBox gives one side of a margin or padding shorthand of one to four
+// lengths; side counts clockwise from the top.
+func cssBox(value string, em float64, side int) (float64, bool) {
+	parts := strings.Fields(value)
+	var part string
+	switch len(parts) {
+	case 1:
+		part = parts[0]
+	case 2:
+		part = parts[side%2]
+	case 3:
+		part = parts[[]int{0, 1, 2, 1}[side]]
+	case 4:
+		part = parts[side]
+	default:
+		return 0, false
+	}
+	return cssLength(part, em)
+}
+
+// cssUnits are the sizes of CSS units in points.
+var cssUnits = map[string]float64{
+	"pt": 1,
+	"px": 0.75,
+	"pc": 12,
+	"in": 72,
+	"cm": 72 / 2.54,
+	"mm": 72 / 25.4,
+}
+
+// cssLength converts a CSS length to points. Lengths in em, rem and % are
+// relative to em.
+func cssLength(value string, em float64) (float64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "0" {
+		return 0, true
+	}
+	number, unit := value, ""
+	for i := len(value) - 1; i >= 0; i-- {
+		if c := value[i]; c >= '0' && c <= '9' || c == '.' {
+			number, unit = value[:i+1], value[i+1:]
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch unit {
+	case "em", "rem":
+		return n * em, true
+	case "%":
+		return n * em / 100, true
+	}
+	if size, ok := cssUnits[unit]; ok {
+		return n * size, true
+	}
+	return 0, false
+}
+
+// cssGenericFonts maps CSS generic families and common font names to the
+// standard PDF fonts.
+var cssGenericFonts = map[string]string{
+	"serif":           serifFont,
+	"sans-serif":      sansFont,
+	"monospace":       monoFont,
+	"times":           serifFont,
+	"times new roman": serifFont,
+	"helvetica":       sansFont,
+	"arial":           sansFont,
+	"courier":         monoFont,
+	"courier new":     monoFont,
+}
+
+// fontFamily chooses the fonts in a font-family list that are either fonts
+// added to Pdf or standard fonts. The first is the font, and the rest are
+// its fallbacks.
+func (r *PdfRenderer) fontFamily(list string) (string, []string) {
+	var fonts []string
+	seen := make(map[string]bool)
+	for _, name := range splitCSS(list, ',') {
+		name = strings.Trim(strings.TrimSpace(name), `"'`)
+		font, ok := cssGenericFonts[strings.ToLower(name)]
+		if !ok && name != "" && r.Pdf.GetFontDesc(name, "").Ascent != 0 {
+			font, ok = name, true
+		}
+		if ok && !seen[strings.ToLower(font)] {
+			seen[strings.ToLower(font)] = true
+			fonts = append(fonts, font)
+		}
+	}
+	if len(fonts) == 0 {
+		return "", nil
+	}
+	return fonts[0], fonts[1:]
+}
+
+// setStyle adds or removes a gofpdf style letter, such as "b" for bold.
+func setStyle(style, letter string, on bool) string {
+	style = strings.NewReplacer(letter, "", strings.ToUpper(letter), "").Replace(style)
+	if on {
+		style += letter
+	}
+	return style
+}
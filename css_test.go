@@ -0,0 +1,167 @@
+package mdtopdf
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseCSS(t *testing.T) {
+	rules, err := parseCSS(`
+/* a comment { with a brace */
+h1, H2 { color: #333; font-family: "Times New Roman", serif }
+@media print { p { color: red } }
+@page { margin: 1in }
+p { }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("got %d rules: %+v", len(rules), rules)
+	}
+	if sel := rules[0].selectors; len(sel) != 2 || sel[0] != "h1" || sel[1] != "h2" {
+		t.Errorf("got selectors %q", sel)
+	}
+	if d := rules[0].decls; len(d) != 2 || d[1] != [2]string{"font-family", `"Times New Roman", serif`} {
+		t.Errorf("got declarations %q", d)
+	}
+	if rules[1].selectors[0] != "@page" {
+		t.Errorf("got %+v", rules[1])
+	}
+
+	for _, bad := range []string{"p { color: red", "p color: red }"} {
+		if _, err := parseCSS(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestCSSLength(t *testing.T) {
+	cases := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"12pt", 12, true},
+		{"16px", 12, true},
+		{"1in", 72, true},
+		{"2.54cm", 72, true},
+		{"1.5em", 15, true},
+		{"2rem", 20, true},
+		{"120%", 12, true},
+		{"0", 0, true},
+		{"-1em", -10, true},
+		{"12", 0, false},
+		{"auto", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := cssLength(c.value, 10)
+		if ok != c.ok || math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%q: got %v, %v; want %v, %v", c.value, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestSetStylesheet(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	err := r.SetStylesheet(`
+p { font-family: -apple-system, "Segoe UI", Georgia, serif; font-size: 11pt; line-height: 1.5; text-align: justify }
+h1 { font-size: 2em; font-weight: normal; font-style: italic; color: #036; border-bottom: 1px solid #eee }
+code, pre { font-family: monospace; background-color: #f6f8fa }
+blockquote { margin: 0; padding: 0 1em; color: #6a737d }
+table { border: 1px solid rgb(223, 226, 229) }
+pre { background-color: transparent }
+th { font-weight: 600 }
+a { color: #0366d6 !important }
+.markdown-body p { color: #f00 }
+div { color: #f00 }
+@page { margin: 72pt 36pt }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Normal.Font != serifFont || r.Normal.Size != 11 || r.Normal.Spacing != 5.5 || r.Normal.Align != AlignJustify {
+		t.Errorf("got Normal %+v", r.Normal)
+	}
+	if r.Normal.TextColor != Black {
+		t.Errorf("got Normal %+v; want class selectors ignored", r.Normal)
+	}
+	if r.H1.Size != 22 || r.H1.Style != "i" || r.H1.TextColor != (Color{0, 0x33, 0x66}) {
+		t.Errorf("got H1 %+v", r.H1)
+	}
+	if r.Backtick.Font != monoFont || r.Backtick.FillColor != White {
+		t.Errorf("got Backtick %+v", r.Backtick)
+	}
+	if r.QuoteIndent != 11 || r.Blockquote.TextColor != (Color{0x6a, 0x73, 0x7d}) {
+		t.Errorf("got quote indent %v, Blockquote %+v", r.QuoteIndent, r.Blockquote)
+	}
+	if r.BorderColor != (Color{0xdf, 0xe2, 0xe5}) || r.THeader.Style != "b" {
+		t.Errorf("got border %v, THeader %+v", r.BorderColor, r.THeader)
+	}
+	if r.Link.TextColor != (Color{0x03, 0x66, 0xd6}) || r.Link.Style != "u" {
+		t.Errorf("got Link %+v", r.Link)
+	}
+	lm, tm, rm, _ := r.Pdf.GetMargins()
+	if lm != 36 || tm != 72 || rm != 36 {
+		t.Errorf("got margins %v %v %v", lm, tm, rm)
+	}
+}
+
+func TestStylesheetBoxes(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	indent := r.IndentValue
+	err := r.SetStylesheet(`
+p { margin: 0 0 6pt; line-height: 1.5 }
+h2 { padding-top: 4pt; margin-top: 10pt }
+pre { margin: 0 }
+blockquote { margin-left: 1em; padding-left: 1em }
+li { padding-left: 15pt }
+th, td { padding: 3pt 6pt }
+h1 { border-bottom: 1px solid #eee; margin-right: 2em }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// vertical margins and paddings of blocks are not applied
+	if r.Normal.Spacing != 5 || r.H2.Spacing != 6 || r.Backtick.Spacing != 4 {
+		t.Errorf("got spacing %v, %v, %v", r.Normal.Spacing, r.H2.Spacing, r.Backtick.Spacing)
+	}
+	if r.THeader.Spacing != 6 || r.TBody.Spacing != 6 {
+		t.Errorf("got cell spacing %v, %v", r.THeader.Spacing, r.TBody.Spacing)
+	}
+	// block quotes and lists are indented separately
+	if r.QuoteIndent != 20 || r.IndentValue != 15 {
+		t.Errorf("got indents %v, %v; the default was %v", r.QuoteIndent, r.IndentValue, indent)
+	}
+
+	var got []string
+	for _, w := range r.StylesheetWarnings() {
+		got = append(got, w.Selector+" "+w.Property)
+	}
+	want := []string{"p margin", "h2 padding-top", "h2 margin-top", "th padding", "td padding", "h1 border-bottom", "h1 margin-right"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got warnings %q; want %q", got, want)
+	}
+}
+
+func TestSetStyle(t *testing.T) {
+	cases := []struct {
+		style, letter string
+		on            bool
+		want          string
+	}{
+		{"", "b", true, "b"},
+		{"B", "b", true, "b"},
+		{"Bu", "b", false, "u"},
+		{"i", "b", true, "ib"},
+		{"bi", "i", false, "b"},
+	}
+	for _, c := range cases {
+		if got := setStyle(c.style, c.letter, c.on); got != c.want {
+			t.Errorf("%+v: got %q", c, got)
+		}
+	}
+}
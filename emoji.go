@@ -0,0 +1,263 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// Emoji shortcodes such as ":rocket:" are replaced by the emoji they name,
+// if it can be drawn; otherwise the shortcode is more readable than the
+// emoji would be. The standard PDF fonts have no emoji, and TrueType fonts
+// draw them in one colour if at all, so when EmojiImages is set each emoji
+// is drawn instead as an inline image, scaled to the height of the line
+// like any other. The images are named by their code points in hex, as the
+// Twemoji set names them, e.g. "1f680.png" for a rocket or
+// "1f44d-1f3fd.png" for a thumbs up with a skin tone; SVG images may be
+// used too. Noto's names, such as "emoji_u1f680.png", are also found. An
+// emoji without an image is written as text.
+
+var shortcodePattern = regexp.MustCompile(`:[a-z0-9_+-]+:`)
+
+// expandShortcodes replaces the emoji shortcodes that it knows in text by
+// the emoji that drawable reports can be drawn.
+func expandShortcodes(text string, drawable func(emoji string) bool) string {
+	if !strings.Contains(text, ":") {
+		return text
+	}
+	return shortcodePattern.ReplaceAllStringFunc(text, func(code string) string {
+		if emoji, ok := emojiShortcodes[code[1:len(code)-1]]; ok && drawable(emoji) {
+			return emoji
+		}
+		return code
+	})
+}
+
+// canDrawEmoji reports whether an emoji can be drawn in a style: with an
+// image, except in a table cell, or else with the glyphs of its fonts.
+func (r *PdfRenderer) canDrawEmoji(s Styler, emoji string) bool {
+	chars := []rune(emoji)
+	if r.EmojiImages != nil && r.cs.peek().containerType != bf.TableCell && r.emojiImage(chars) != nil {
+		return true
+	}
+	chain := fontChain(s)
+	for _, ch := range chars {
+		if ch == variationEmoji || ch == zwj {
+			continue
+		}
+		if !r.hasGlyph(r.fontFor(chain, ch), ch) {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	variationEmoji = 0xfe0f // emoji presentation selector
+	keycap         = 0x20e3
+)
+
+// emojiPresentation are the characters below U+1F000 that are emoji
+// without a following variation selector; others, such as "©", are text
+// unless they have one.
+var emojiPresentation = map[rune]bool{
+	0x231a: true, 0x231b: true, 0x23e9: true, 0x23ea: true, 0x23eb: true, 0x23ec: true,
+	0x23f0: true, 0x23f3: true, 0x25fd: true, 0x25fe: true, 0x2614: true, 0x2615: true,
+	0x2648: true, 0x2649: true, 0x264a: true, 0x264b: true, 0x264c: true, 0x264d: true,
+	0x264e: true, 0x264f: true, 0x2650: true, 0x2651: true, 0x2652: true, 0x2653: true,
+	0x267f: true, 0x2693: true, 0x26a1: true, 0x26aa: true, 0x26ab: true, 0x26bd: true,
+	0x26be: true, 0x26c4: true, 0x26c5: true, 0x26ce: true, 0x26d4: true, 0x26ea: true,
+	0x26f2: true, 0x26f3: true, 0x26f5: true, 0x26fa: true, 0x26fd: true, 0x2705: true,
+	0x270a: true, 0x270b: true, 0x2728: true, 0x274c: true, 0x274e: true, 0x2753: true,
+	0x2754: true, 0x2755: true, 0x2757: true, 0x2795: true, 0x2796: true, 0x2797: true,
+	0x27b0: true, 0x27bf: true, 0x2b1b: true, 0x2b1c: true, 0x2b50: true, 0x2b55: true,
+}
+
+func isRegionalIndicator(ch rune) bool {
+	return ch >= 0x1f1e6 && ch <= 0x1f1ff
+}
+
+func isSkinTone(ch rune) bool {
+	return ch >= 0x1f3fb && ch <= 0x1f3ff
+}
+
+// emojiLength gives the number of characters of the emoji starting at
+// chars[0], or zero if there isn't one. An emoji may be a sequence: a flag
+// made of two regional indicators, a keycap, or emoji joined by zero
+// width joiners, each perhaps with a skin tone.
+func emojiLength(chars []rune) int {
+	at := func(i int) rune {
+		if i < len(chars) {
+			return chars[i]
+		}
+		return 0
+	}
+
+	ch := chars[0]
+	switch {
+	case isRegionalIndicator(ch):
+		if isRegionalIndicator(at(1)) {
+			return 2
+		}
+		return 0
+	case (ch >= '0' && ch <= '9') || ch == '#' || ch == '*':
+		if at(1) == keycap {
+			return 2
+		}
+		if at(1) == variationEmoji && at(2) == keycap {
+			return 3
+		}
+		return 0
+	}
+
+	n, end := 0, 0 // end is that of the last whole emoji
+	for {
+		ch := at(n)
+		switch {
+		case ch >= 0x1f000 && ch <= 0x1faff, emojiPresentation[ch]:
+			n++
+		case ch >= 0x2000 && ch < 0x3300 || ch == 0xa9 || ch == 0xae:
+			if at(n+1) != variationEmoji {
+				return end
+			}
+			n++
+		default:
+			return end
+		}
+		if at(n) == variationEmoji {
+			n++
+		}
+		if isSkinTone(at(n)) {
+			n++
+		}
+		end = n
+		if at(n) != zwj {
+			return end
+		}
+		n++ // the joiner, which must be followed by another emoji
+	}
+}
+
+// emojiFileNames gives the names an emoji's image may have, without their
+// extension.
+func emojiFileNames(emoji []rune) []string {
+	var all, bare []string
+	joined := false
+	for _, ch := range emoji {
+		code := fmt.Sprintf("%x", ch)
+		all = append(all, code)
+		if ch != variationEmoji {
+			bare = append(bare, code)
+		}
+		if ch == zwj {
+			joined = true
+		}
+	}
+	// Twemoji keeps the variation selector only in joined sequences
+	names := []string{strings.Join(bare, "-"), strings.Join(all, "-")}
+	if joined {
+		names[0], names[1] = names[1], names[0]
+	}
+	return append(names, "emoji_u"+strings.Join(bare, "_"))
+}
+
+// emojiImage finds and loads the image for an emoji, or gives nil if
+// there is none.
+func (r *PdfRenderer) emojiImage(emoji []rune) pdfImage {
+	for _, name := range emojiFileNames(emoji) {
+		for _, ext := range []string{".png", ".svg"} {
+			file := name + ext
+			key := "emoji:" + file
+			img, ok := r.images[key]
+			if !ok {
+				// remember the files that are missing too
+				img = r.readEmoji(key, file)
+				if r.images == nil {
+					r.images = make(map[string]pdfImage)
+				}
+				r.images[key] = img
+			}
+			if img != nil {
+				return img
+			}
+		}
+	}
+	return nil
+}
+
+func (r *PdfRenderer) readEmoji(key, file string) pdfImage {
+	data, err := fs.ReadFile(r.EmojiImages, file)
+	if err != nil {
+		return nil
+	}
+	img, err := r.decodeImage(key, data)
+	if err != nil {
+		r.tracer("... Emoji", err.Error())
+		return nil
+	}
+	return img
+}
+
+// placeEmoji draws an emoji's image in the flow of the text, linked if it
+// is within a link.
+func (r *PdfRenderer) placeEmoji(img pdfImage) {
+	url := ""
+	if c := r.cs.peek(); c.containerType == bf.Link {
+		url = c.destination
+	}
+	r.placeInlineImage(img, imageHints{}, url)
+}
+
+// emojiSpan is a piece of text, or an emoji that has an image.
+type emojiSpan struct {
+	text string
+	img  pdfImage
+}
+
+// emojiSpans splits text into plain text and the emoji that have images.
+// It gives nil if there are none.
+func (r *PdfRenderer) emojiSpans(text string) []emojiSpan {
+	if r.EmojiImages == nil {
+		return nil
+	}
+	chars := []rune(text)
+	var spans []emojiSpan
+	found := false
+	start := 0
+	for i := 0; i < len(chars); {
+		n := emojiLength(chars[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		img := r.emojiImage(chars[i : i+n])
+		if img != nil {
+			if i > start {
+				spans = append(spans, emojiSpan{text: string(chars[start:i])})
+			}
+			spans = append(spans, emojiSpan{text: string(chars[i : i+n]), img: img})
+			found = true
+			start = i + n
+		}
+		i += n
+	}
+	if !found {
+		return nil
+	}
+	if start < len(chars) {
+		spans = append(spans, emojiSpan{text: string(chars[start:])})
+	}
+	return spans
+}
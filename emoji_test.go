@@ -0,0 +1,131 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExpandShortcodes(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"no codes", "no codes"},
+		{"launch :rocket: now", "launch 🚀 now"},
+		{":+1::tada:", "👍🎉"},
+		{":not_an_emoji: stays", ":not_an_emoji: stays"},
+		{"time 10:30:45", "time 10:30:45"},
+	}
+	all := func(string) bool { return true }
+	for _, c := range cases {
+		if got := expandShortcodes(c.in, all); got != c.want {
+			t.Errorf("expandShortcodes(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	// emoji that can't be drawn are left as shortcodes
+	rocket := func(emoji string) bool { return emoji == "🚀" }
+	if got := expandShortcodes(":rocket: and :tada:", rocket); got != "🚀 and :tada:" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestShortcodesNotDrawable(t *testing.T) {
+	// the standard fonts have no emoji, so without images the shortcode
+	// is written as it is
+	r := NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	var buf bytes.Buffer
+	if err := r.Process([]byte("Launch :rocket: now\n")).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(":rocket:")) {
+		t.Errorf("the shortcode was not kept")
+	}
+	if bytes.Contains(buf.Bytes(), []byte("\U0001f680")) {
+		t.Errorf("the emoji was written in a standard font")
+	}
+}
+
+func TestEmojiLength(t *testing.T) {
+	cases := []struct {
+		text string
+		want int
+	}{
+		{"🚀 go", 1},
+		{"a", 0},
+		{"©", 0},    // text unless it has a variation selector
+		{"©️", 2},   // ...as here
+		{"⭐", 1},    // an emoji by default
+		{"👍🏽", 2},   // with a skin tone
+		{"🇬🇧", 2},   // a flag
+		{"1️⃣", 3},  // a keycap
+		{"1 ", 0},   // just a digit
+		{"👩‍💻", 3},  // joined
+		{"❤️‍🔥", 4}, // joined after a selector
+		{"🏃‍♀️", 4}, // a joined sign with a selector
+		{"🏃‍x", 1},  // a joiner with nothing to join
+	}
+	for _, c := range cases {
+		if got := emojiLength([]rune(c.text)); got != c.want {
+			t.Errorf("emojiLength(%q) = %d, want %d", c.text, got, c.want)
+		}
+	}
+}
+
+func TestEmojiFileNames(t *testing.T) {
+	cases := []struct {
+		emoji string
+		want  []string
+	}{
+		{"🚀", []string{"1f680", "1f680", "emoji_u1f680"}},
+		{"❤️", []string{"2764", "2764-fe0f", "emoji_u2764"}},
+		{"👩‍💻", []string{"1f469-200d-1f4bb", "1f469-200d-1f4bb", "emoji_u1f469_200d_1f4bb"}},
+		{"🏃‍♀️", []string{"1f3c3-200d-2640-fe0f", "1f3c3-200d-2640", "emoji_u1f3c3_200d_2640"}},
+	}
+	for _, c := range cases {
+		if got := emojiFileNames([]rune(c.emoji)); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("emojiFileNames(%q) = %v, want %v", c.emoji, got, c.want)
+		}
+	}
+}
+
+func TestEmojiImages(t *testing.T) {
+	png, err := ioutil.ReadFile("testdata/emoji/1f315.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewPdfRenderer("", "", "")
+	r.EmojiImages = fstest.MapFS{"emoji_u1f680.png": &fstest.MapFile{Data: png}}
+
+	err = r.Process([]byte("# Up :rocket:\n\nLift off 🚀 and :tada:\n")).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.GetImageInfo("emoji:emoji_u1f680.png") == nil {
+		t.Errorf("emoji image was not used")
+	}
+	if img, ok := r.images["emoji:1f389.png"]; !ok || img != nil {
+		t.Errorf("a missing emoji image should be remembered as missing")
+	}
+}
+
+func TestEmojiShortcodesOff(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.EmojiShortcodes = false
+	r.EmojiImages = os.DirFS("testdata/emoji")
+	err := r.Process([]byte("Lift off :rocket:\n")).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.images["emoji:1f680.svg"]; ok {
+		t.Errorf("shortcode was expanded")
+	}
+}
+
+func TestEmoji(t *testing.T) {
+	testit("Emoji.md", t, func(r *PdfRenderer) {
+		r.EmojiImages = os.DirFS("testdata/emoji")
+	})
+}
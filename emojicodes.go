@@ -0,0 +1,479 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+// emojiShortcodes are the commonly used emoji shortcodes of GitHub and
+// Slack, without their colons.
+var emojiShortcodes = map[string]string{
+	"+1":                           "👍",
+	"-1":                           "👎",
+	"100":                          "💯",
+	"1st_place_medal":              "🥇",
+	"2nd_place_medal":              "🥈",
+	"3rd_place_medal":              "🥉",
+	"abacus":                       "🧮",
+	"airplane":                     "✈️",
+	"alarm_clock":                  "⏰",
+	"alien":                        "👽",
+	"angry":                        "😠",
+	"ant":                          "🐜",
+	"apple":                        "🍎",
+	"arrow_backward":               "◀️",
+	"arrow_down":                   "⬇️",
+	"arrow_forward":                "▶️",
+	"arrow_left":                   "⬅️",
+	"arrow_right":                  "➡️",
+	"arrow_up":                     "⬆️",
+	"arrows_counterclockwise":      "🔄",
+	"art":                          "🎨",
+	"artist":                       "🧑‍🎨",
+	"asterisk":                     "*️⃣",
+	"astonished":                   "😲",
+	"avocado":                      "🥑",
+	"baby":                         "👶",
+	"bacon":                        "🥓",
+	"balloon":                      "🎈",
+	"ballot_box_with_check":        "☑️",
+	"banana":                       "🍌",
+	"bangbang":                     "‼️",
+	"bank":                         "🏦",
+	"bar_chart":                    "📊",
+	"baseball":                     "⚾",
+	"basketball":                   "🏀",
+	"battery":                      "🔋",
+	"beach_umbrella":               "⛱",
+	"bear":                         "🐻",
+	"bee":                          "🐝",
+	"beer":                         "🍺",
+	"beers":                        "🍻",
+	"beetle":                       "🪲",
+	"bell":                         "🔔",
+	"bike":                         "🚲",
+	"bird":                         "🐦",
+	"birthday":                     "🎂",
+	"black_circle":                 "⚫",
+	"black_heart":                  "🖤",
+	"black_nib":                    "✒️",
+	"blossom":                      "🌼",
+	"blue_heart":                   "💙",
+	"blush":                        "😊",
+	"book":                         "📖",
+	"bookmark":                     "🔖",
+	"books":                        "📚",
+	"boom":                         "💥",
+	"bouquet":                      "💐",
+	"bowling":                      "🎳",
+	"boy":                          "👦",
+	"brain":                        "🧠",
+	"bread":                        "🍞",
+	"briefcase":                    "💼",
+	"broken_heart":                 "💔",
+	"broom":                        "🧹",
+	"bug":                          "🐛",
+	"building_construction":        "🏗️",
+	"bulb":                         "💡",
+	"burrito":                      "🌯",
+	"butterfly":                    "🦋",
+	"cactus":                       "🌵",
+	"cake":                         "🍰",
+	"calendar":                     "📆",
+	"camping":                      "🏕️",
+	"candy":                        "🍬",
+	"car":                          "🚗",
+	"card_index_dividers":          "🗂️",
+	"carrot":                       "🥕",
+	"cat":                          "🐱",
+	"chains":                       "⛓️",
+	"champagne":                    "🍾",
+	"chart_with_downwards_trend":   "📉",
+	"chart_with_upwards_trend":     "📈",
+	"checkered_flag":               "🏁",
+	"cheese":                       "🧀",
+	"cherries":                     "🍒",
+	"cherry_blossom":               "🌸",
+	"chicken":                      "🐔",
+	"chocolate_bar":                "🍫",
+	"city_sunset":                  "🌆",
+	"clap":                         "👏",
+	"clipboard":                    "📋",
+	"closed_lock_with_key":         "🔐",
+	"cloud":                        "☁️",
+	"clown_face":                   "🤡",
+	"cocktail":                     "🍸",
+	"coconut":                      "🥥",
+	"coffee":                       "☕",
+	"collision":                    "💥",
+	"computer":                     "💻",
+	"computer_mouse":               "🖱",
+	"confetti_ball":                "🎊",
+	"confounded":                   "😖",
+	"confused":                     "😕",
+	"construction":                 "🚧",
+	"construction_worker":          "👷‍♂️",
+	"cookie":                       "🍪",
+	"cool":                         "🆒",
+	"cop":                          "👮‍♂️",
+	"copyright":                    "©️",
+	"corn":                         "🌽",
+	"cow":                          "🐮",
+	"crayon":                       "🖍",
+	"credit_card":                  "💳",
+	"crescent_moon":                "🌙",
+	"crossed_fingers":              "🤞",
+	"crown":                        "👑",
+	"cry":                          "😢",
+	"dark_sunglasses":              "🕶️",
+	"dart":                         "🎯",
+	"date":                         "📅",
+	"deciduous_tree":               "🌳",
+	"desert":                       "🏜️",
+	"desktop_computer":             "🖥️",
+	"detective":                    "🕵",
+	"disappointed":                 "😞",
+	"dizzy":                        "💫",
+	"dna":                          "🧬",
+	"dog":                          "🐶",
+	"dollar":                       "💵",
+	"dolphin":                      "🐬",
+	"doughnut":                     "🍩",
+	"dragon":                       "🐉",
+	"dress":                        "👗",
+	"droplet":                      "💧",
+	"duck":                         "🦆",
+	"eagle":                        "🦅",
+	"earth_africa":                 "🌍",
+	"earth_americas":               "🌎",
+	"earth_asia":                   "🌏",
+	"egg":                          "🥚",
+	"eggplant":                     "🍆",
+	"eight":                        "8️⃣",
+	"electric_plug":                "🔌",
+	"email":                        "✉️",
+	"envelope":                     "✉",
+	"evergreen_tree":               "🌲",
+	"exclamation":                  "❗",
+	"expressionless":               "😑",
+	"eyeglasses":                   "👓",
+	"eyes":                         "👀",
+	"factory":                      "🏭",
+	"fallen_leaf":                  "🍂",
+	"file_folder":                  "📁",
+	"fire":                         "🔥",
+	"fire_extinguisher":            "🧯",
+	"fish":                         "🐟",
+	"five":                         "5️⃣",
+	"flashlight":                   "🔦",
+	"flushed":                      "😳",
+	"football":                     "🏈",
+	"fountain_pen":                 "🖋",
+	"four":                         "4️⃣",
+	"four_leaf_clover":             "🍀",
+	"fox_face":                     "🦊",
+	"free":                         "🆓",
+	"fries":                        "🍟",
+	"frog":                         "🐸",
+	"full_moon":                    "🌕",
+	"game_die":                     "🎲",
+	"gear":                         "⚙️",
+	"gem":                          "💎",
+	"ghost":                        "👻",
+	"gift":                         "🎁",
+	"girl":                         "👧",
+	"globe_with_meridians":         "🌐",
+	"golf":                         "⛳",
+	"gorilla":                      "🦍",
+	"grapes":                       "🍇",
+	"green_apple":                  "🍏",
+	"green_circle":                 "🟢",
+	"green_heart":                  "💚",
+	"grey_exclamation":             "❕",
+	"grey_question":                "❔",
+	"grimacing":                    "😬",
+	"grin":                         "😁",
+	"hamburger":                    "🍔",
+	"hammer":                       "🔨",
+	"hammer_and_wrench":            "🛠️",
+	"handbag":                      "👜",
+	"handshake":                    "🤝",
+	"hash":                         "#️⃣",
+	"headphones":                   "🎧",
+	"hear_no_evil":                 "🙉",
+	"heart":                        "❤️",
+	"heart_eyes":                   "😍",
+	"heavy_check_mark":             "✔️",
+	"heavy_minus_sign":             "➖",
+	"heavy_multiplication_x":       "✖️",
+	"heavy_plus_sign":              "➕",
+	"herb":                         "🌿",
+	"horse":                        "🐴",
+	"hospital":                     "🏥",
+	"hot_pepper":                   "🌶️",
+	"hotdog":                       "🌭",
+	"hotel":                        "🏨",
+	"hourglass":                    "⌛",
+	"hourglass_flowing_sand":       "⏳",
+	"house":                        "🏠",
+	"house_with_garden":            "🏡",
+	"inbox_tray":                   "📥",
+	"information_source":           "ℹ️",
+	"innocent":                     "😇",
+	"interrobang":                  "⁉️",
+	"iphone":                       "📱",
+	"jeans":                        "👖",
+	"jigsaw":                       "🧩",
+	"joy":                          "😂",
+	"key":                          "🔑",
+	"keyboard":                     "⌨️",
+	"keycap_ten":                   "🔟",
+	"kissing_heart":                "😘",
+	"koala":                        "🐨",
+	"label":                        "🏷️",
+	"large_blue_circle":            "🔵",
+	"large_blue_diamond":           "🔷",
+	"laughing":                     "😆",
+	"leaves":                       "🍃",
+	"lemon":                        "🍋",
+	"link":                         "🔗",
+	"lion":                         "🦁",
+	"lipstick":                     "💄",
+	"lock":                         "🔒",
+	"lock_with_ink_pen":            "🔏",
+	"lollipop":                     "🍭",
+	"loudspeaker":                  "📢",
+	"mag":                          "🔍",
+	"mag_right":                    "🔎",
+	"mage":                         "🧙‍♀️",
+	"magnet":                       "🧲",
+	"mailbox":                      "📫",
+	"man":                          "👨",
+	"man_technologist":             "👨‍💻",
+	"maple_leaf":                   "🍁",
+	"mask":                         "😷",
+	"medal_sports":                 "🏅",
+	"mega":                         "📣",
+	"memo":                         "📝",
+	"microphone":                   "🎤",
+	"microscope":                   "🔬",
+	"milky_way":                    "🌌",
+	"moneybag":                     "💰",
+	"monkey":                       "🐒",
+	"moon":                         "🌔",
+	"mortar_board":                 "🎓",
+	"mountain":                     "⛰️",
+	"mouse":                        "🐭",
+	"muscle":                       "💪",
+	"mushroom":                     "🍄",
+	"musical_note":                 "🎵",
+	"necktie":                      "👔",
+	"negative_squared_cross_mark":  "❎",
+	"nerd_face":                    "🤓",
+	"neutral_face":                 "😐",
+	"new":                          "🆕",
+	"new_moon":                     "🌑",
+	"night_with_stars":             "🌃",
+	"nine":                         "9️⃣",
+	"ninja":                        "🥷",
+	"no_entry":                     "⛔",
+	"no_entry_sign":                "🚫",
+	"no_mouth":                     "😶",
+	"notebook":                     "📓",
+	"notes":                        "🎶",
+	"nut_and_bolt":                 "🔩",
+	"ocean":                        "🌊",
+	"octopus":                      "🐙",
+	"office":                       "🏢",
+	"ok":                           "🆗",
+	"ok_hand":                      "👌",
+	"old_key":                      "🗝️",
+	"older_man":                    "👴",
+	"older_woman":                  "👵",
+	"one":                          "1️⃣",
+	"open_file_folder":             "📂",
+	"open_mouth":                   "😮",
+	"orange_circle":                "🟠",
+	"orange_heart":                 "🧡",
+	"outbox_tray":                  "📤",
+	"owl":                          "🦉",
+	"package":                      "📦",
+	"page_facing_up":               "📄",
+	"paintbrush":                   "🖌",
+	"palm_tree":                    "🌴",
+	"panda_face":                   "🐼",
+	"paperclip":                    "📎",
+	"peach":                        "🍑",
+	"pear":                         "🍐",
+	"pen":                          "🖊",
+	"pencil":                       "✏",
+	"pencil2":                      "✏️",
+	"penguin":                      "🐧",
+	"pensive":                      "😔",
+	"performing_arts":              "🎭",
+	"phone":                        "☎️",
+	"pig":                          "🐷",
+	"pill":                         "💊",
+	"pineapple":                    "🍍",
+	"pizza":                        "🍕",
+	"pleading_face":                "🥺",
+	"point_down":                   "👇",
+	"point_left":                   "👈",
+	"point_right":                  "👉",
+	"point_up":                     "☝️",
+	"poop":                         "💩",
+	"popcorn":                      "🍿",
+	"pray":                         "🙏",
+	"printer":                      "🖨️",
+	"purple_circle":                "🟣",
+	"purple_heart":                 "💜",
+	"pushpin":                      "📌",
+	"question":                     "❓",
+	"rabbit":                       "🐰",
+	"rage":                         "😡",
+	"rainbow":                      "🌈",
+	"raised_hands":                 "🙌",
+	"recycle":                      "♻️",
+	"red_circle":                   "🔴",
+	"red_square":                   "🟥",
+	"registered":                   "®️",
+	"relieved":                     "😌",
+	"repeat":                       "🔁",
+	"ring":                         "💍",
+	"robot":                        "🤖",
+	"rocket":                       "🚀",
+	"rofl":                         "🤣",
+	"roll_eyes":                    "🙄",
+	"rose":                         "🌹",
+	"rotating_light":               "🚨",
+	"round_pushpin":                "📍",
+	"santa":                        "🎅",
+	"satellite":                    "🛰️",
+	"sauropod":                     "🦕",
+	"school":                       "🏫",
+	"school_satchel":               "🎒",
+	"scientist":                    "🧑‍🔬",
+	"scissors":                     "✂️",
+	"scream":                       "😱",
+	"see_no_evil":                  "🙈",
+	"seedling":                     "🌱",
+	"seven":                        "7️⃣",
+	"shield":                       "🛡️",
+	"ship":                         "🚢",
+	"shirt":                        "👕",
+	"shushing_face":                "🤫",
+	"six":                          "6️⃣",
+	"skull":                        "💀",
+	"sleeping":                     "😴",
+	"sleepy":                       "😪",
+	"slightly_smiling_face":        "🙂",
+	"small_orange_diamond":         "🔸",
+	"smile":                        "😄",
+	"smiley":                       "😃",
+	"smiley_cat":                   "😺",
+	"smirk":                        "😏",
+	"snail":                        "🐌",
+	"snake":                        "🐍",
+	"snowflake":                    "❄️",
+	"soap":                         "🧼",
+	"sob":                          "😭",
+	"soccer":                       "⚽",
+	"soon":                         "🔜",
+	"sos":                          "🆘",
+	"sparkles":                     "✨",
+	"sparkling_heart":              "💖",
+	"speak_no_evil":                "🙊",
+	"speech_balloon":               "💬",
+	"spiral_calendar":              "🗓",
+	"sponge":                       "🧽",
+	"star":                         "⭐",
+	"star2":                        "🌟",
+	"star_struck":                  "🤩",
+	"stethoscope":                  "🩺",
+	"stop_sign":                    "🛑",
+	"stopwatch":                    "⏱️",
+	"straight_ruler":               "📏",
+	"strawberry":                   "🍓",
+	"stuck_out_tongue_winking_eye": "😜",
+	"student":                      "🧑‍🎓",
+	"sunflower":                    "🌻",
+	"sunglasses":                   "😎",
+	"sunny":                        "☀️",
+	"sunrise":                      "🌅",
+	"superhero":                    "🦸",
+	"sweat":                        "😓",
+	"sweat_smile":                  "😅",
+	"syringe":                      "💉",
+	"t-rex":                        "🦖",
+	"taco":                         "🌮",
+	"tada":                         "🎉",
+	"tangerine":                    "🍊",
+	"tea":                          "🍵",
+	"teacher":                      "🧑‍🏫",
+	"technologist":                 "🧑‍💻",
+	"telephone_receiver":           "📞",
+	"telescope":                    "🔭",
+	"tennis":                       "🎾",
+	"test_tube":                    "🧪",
+	"thinking":                     "🤔",
+	"thought_balloon":              "💭",
+	"three":                        "3️⃣",
+	"thumbsdown":                   "👎",
+	"thumbsup":                     "👍",
+	"tiger":                        "🐯",
+	"timer_clock":                  "⏲️",
+	"tired_face":                   "😫",
+	"tm":                           "™️",
+	"tomato":                       "🍅",
+	"toolbox":                      "🧰",
+	"top":                          "🔝",
+	"tophat":                       "🎩",
+	"triangular_flag_on_post":      "🚩",
+	"triangular_ruler":             "📐",
+	"triumph":                      "😤",
+	"trophy":                       "🏆",
+	"tropical_fish":                "🐠",
+	"tulip":                        "🌷",
+	"turtle":                       "🐢",
+	"two":                          "2️⃣",
+	"two_hearts":                   "💕",
+	"umbrella":                     "☂️",
+	"unamused":                     "😒",
+	"unicorn":                      "🦄",
+	"unlock":                       "🔓",
+	"up":                           "🆙",
+	"v":                            "✌️",
+	"vertical_traffic_light":       "🚦",
+	"video_game":                   "🎮",
+	"volcano":                      "🌋",
+	"warning":                      "⚠️",
+	"wastebasket":                  "🗑️",
+	"watch":                        "⌚",
+	"watermelon":                   "🍉",
+	"wave":                         "👋",
+	"weary":                        "😩",
+	"whale":                        "🐳",
+	"white_check_mark":             "✅",
+	"white_circle":                 "⚪",
+	"white_heart":                  "🤍",
+	"wine_glass":                   "🍷",
+	"wink":                         "😉",
+	"woman":                        "👩",
+	"woman_technologist":           "👩‍💻",
+	"world_map":                    "🗺️",
+	"worried":                      "😟",
+	"wrench":                       "🔧",
+	"writing_hand":                 "✍️",
+	"x":                            "❌",
+	"yawning_face":                 "🥱",
+	"yellow_circle":                "🟡",
+	"yellow_heart":                 "💛",
+	"yum":                          "😋",
+	"zap":                          "⚡",
+	"zero":                         "0️⃣",
+	"zipper_mouth_face":            "🤐",
+}
@@ -0,0 +1,271 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A font that lacks a glyph draws nothing for it. So that such characters
+// still appear, a styler may list FallbackFonts; text is split into spans
+// that each use the first font of the chain - Font, then the fallbacks -
+// that has glyphs for them. Which glyphs a font has is read from its
+// character map when it is registered with RegisterFontFamily. The standard
+// PDF fonts are taken to have only ASCII, as text is not translated into
+// their encoding, and fonts added directly to Pdf to have everything.
+
+// runeRange is an inclusive range of characters.
+type runeRange struct {
+	lo, hi rune
+}
+
+// glyphCoverage is the set of characters that a font has glyphs for, as
+// sorted ranges.
+type glyphCoverage []runeRange
+
+func (c glyphCoverage) has(ch rune) bool {
+	i := sort.Search(len(c), func(i int) bool { return c[i].hi >= ch })
+	return i < len(c) && c[i].lo <= ch
+}
+
+// add appends a range, merging it with the last one if they touch. Ranges
+// must be added in order.
+func (c glyphCoverage) add(lo, hi rune) glyphCoverage {
+	if n := len(c); n > 0 && c[n-1].hi+1 >= lo {
+		if hi > c[n-1].hi {
+			c[n-1].hi = hi
+		}
+		return c
+	}
+	return append(c, runeRange{lo, hi})
+}
+
+var errNoCharacterMap = errors.New("font has no Unicode character map")
+
+// parseCoverage reads the Unicode character map of a TrueType or OpenType
+// font, preferring the full map (format 12) to the one limited to the
+// Basic Multilingual Plane (format 4).
+func parseCoverage(font []byte) (glyphCoverage, error) {
+	cmap, err := sfntTable(font, "cmap")
+	if err != nil {
+		return nil, err
+	}
+	if len(cmap) < 4 {
+		return nil, errNoCharacterMap
+	}
+
+	var best []byte
+	bestFormat := uint16(0)
+	for i := 0; i < int(be16(cmap, 2)); i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(cmap) {
+			break
+		}
+		platform, encoding := be16(cmap, rec), be16(cmap, rec+2)
+		isUnicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		off := int(be32(cmap, rec+4))
+		if !isUnicode || off+2 > len(cmap) {
+			continue
+		}
+		format := be16(cmap, off)
+		if (format == 12 || format == 4) && format > bestFormat {
+			best, bestFormat = cmap[off:], format
+		}
+	}
+
+	switch bestFormat {
+	case 12:
+		return cmapFormat12(best)
+	case 4:
+		return cmapFormat4(best)
+	}
+	return nil, errNoCharacterMap
+}
+
+// sfntTable finds a table in a font file.
+func sfntTable(font []byte, tag string) ([]byte, error) {
+	if len(font) < 12 {
+		return nil, errors.New("font file is too short")
+	}
+	for i := 0; i < int(be16(font, 4)); i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(font) {
+			break
+		}
+		if string(font[rec:rec+4]) == tag {
+			off, length := int(be32(font, rec+8)), int(be32(font, rec+12))
+			if off < 0 || length < 0 || off+length > len(font) {
+				break
+			}
+			return font[off : off+length], nil
+		}
+	}
+	return nil, errNoCharacterMap
+}
+
+// cmapFormat4 reads a segment mapping to delta values.
+func cmapFormat4(t []byte) (glyphCoverage, error) {
+	if len(t) < 14 {
+		return nil, errNoCharacterMap
+	}
+	segX2 := int(be16(t, 6))
+	ends := 14
+	starts := ends + segX2 + 2
+	offsets := starts + 2*segX2
+	if offsets+segX2 > len(t) {
+		return nil, errNoCharacterMap
+	}
+
+	var cov glyphCoverage
+	for i := 0; i < segX2/2; i++ {
+		start, end := rune(be16(t, starts+2*i)), rune(be16(t, ends+2*i))
+		rangeOffset := int(be16(t, offsets+2*i))
+		if start > end || start == 0xffff {
+			continue
+		}
+		if rangeOffset == 0 {
+			cov = cov.add(start, end)
+			continue
+		}
+		// the glyph ids are in an array, where zero means there is none
+		for c := start; c <= end; c++ {
+			at := offsets + 2*i + rangeOffset + 2*int(c-start)
+			if at+2 > len(t) {
+				break
+			}
+			if be16(t, at) != 0 {
+				cov = cov.add(c, c)
+			}
+		}
+	}
+	return cov, nil
+}
+
+// cmapFormat12 reads a segmented coverage.
+func cmapFormat12(t []byte) (glyphCoverage, error) {
+	if len(t) < 16 {
+		return nil, errNoCharacterMap
+	}
+	var cov glyphCoverage
+	for i := 0; i < int(be32(t, 12)); i++ {
+		at := 16 + 12*i
+		if at+12 > len(t) {
+			break
+		}
+		start, end := rune(be32(t, at)), rune(be32(t, at+4))
+		if start <= end {
+			cov = cov.add(start, end)
+		}
+	}
+	return cov, nil
+}
+
+func be16(b []byte, at int) uint16 {
+	return binary.BigEndian.Uint16(b[at:])
+}
+
+func be32(b []byte, at int) uint32 {
+	return binary.BigEndian.Uint32(b[at:])
+}
+
+// standardFonts are the fonts built into PDF readers.
+var standardFonts = map[string]bool{
+	"courier": true, "helvetica": true, "arial": true, "times": true, "symbol": true, "zapfdingbats": true,
+}
+
+// hasGlyph reports whether a font has a glyph for a character.
+func (r *PdfRenderer) hasGlyph(font string, ch rune) bool {
+	font = strings.ToLower(font)
+	if cov, ok := r.fontCoverage[font]; ok {
+		return cov.has(ch)
+	}
+	if standardFonts[font] {
+		return ch < 0x80
+	}
+	return true
+}
+
+// fontChain gives the styler's font followed by its fallbacks.
+func fontChain(s Styler) []string {
+	chain := []string{s.Font}
+	for _, f := range strings.Split(s.FallbackFonts, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			chain = append(chain, f)
+		}
+	}
+	return chain
+}
+
+// fontFor gives the first font of a chain that has a glyph for a
+// character, or the first font if none does.
+func (r *PdfRenderer) fontFor(chain []string, ch rune) string {
+	for _, font := range chain {
+		if r.hasGlyph(font, ch) {
+			return font
+		}
+	}
+	return chain[0]
+}
+
+// fontSpan is a piece of text and the style to write it in.
+type fontSpan struct {
+	style Styler
+	text  string
+}
+
+// fontSpans splits text where it needs to change font, according to the
+// styler's fallbacks. Spaces stay in the font before them if it has them,
+// so that words aren't broken up needlessly.
+func (r *PdfRenderer) fontSpans(s Styler, text string) []fontSpan {
+	chain := fontChain(s)
+	if len(chain) == 1 {
+		return []fontSpan{{s, text}}
+	}
+
+	var spans []fontSpan
+	current, start := "", 0
+	for i, ch := range text {
+		font := current
+		if current == "" || !unicode.IsSpace(ch) || !r.hasGlyph(current, ch) {
+			font = r.fontFor(chain, ch)
+		}
+		if font != current {
+			if i > start {
+				spans = append(spans, fontSpan{withFont(s, current), text[start:i]})
+			}
+			current, start = font, i
+		}
+	}
+	if start < len(text) {
+		spans = append(spans, fontSpan{withFont(s, current), text[start:]})
+	}
+	return spans
+}
+
+func withFont(s Styler, font string) Styler {
+	s.Font = font
+	return s
+}
+
+// SetFallbackFonts gives every styler the same fallback fonts, in the
+// order they are to be tried, e.g. a CJK font and then a symbol font.
+func (r *PdfRenderer) SetFallbackFonts(fonts ...string) {
+	list := strings.Join(fonts, ", ")
+	tl := &r.TitleLayout
+	for _, s := range []*Styler{&r.Normal, &r.Link, &r.Backtick, &r.Blockquote,
+		&r.H1, &r.H2, &r.H3, &r.H4, &r.H5, &r.H6, &r.THeader, &r.TBody, &r.Caption,
+		&tl.Title, &tl.Subtitle, &tl.Authors, &tl.Date, &tl.Version} {
+		s.FallbackFonts = list
+	}
+}
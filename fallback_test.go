@@ -0,0 +1,121 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestGlyphCoverage(t *testing.T) {
+	var cov glyphCoverage
+	cov = cov.add(0x20, 0x7e)
+	cov = cov.add(0x7f, 0x80)
+	cov = cov.add(0x100, 0x17f)
+	cov = cov.add(0x150, 0x160)
+	want := glyphCoverage{{0x20, 0x80}, {0x100, 0x17f}}
+	if !reflect.DeepEqual(cov, want) {
+		t.Errorf("got %v; want %v", cov, want)
+	}
+	for ch, has := range map[rune]bool{0x1f: false, ' ': true, 0x80: true, 0xff: false, 0x100: true, 0x17f: true, 0x4e00: false} {
+		if cov.has(ch) != has {
+			t.Errorf("%U: got %v", ch, !has)
+		}
+	}
+}
+
+// cmapFont builds a font file with just a format 4 character map, having
+// one segment mapped by delta and one through the glyph id array.
+func cmapFont() []byte {
+	be := binary.BigEndian
+	u16 := func(b *bytes.Buffer, vs ...uint16) {
+		for _, v := range vs {
+			binary.Write(b, be, v)
+		}
+	}
+
+	var sub bytes.Buffer
+	u16(&sub, 4, 0, 0, 3*2, 0, 0, 0) // format, length, language, segCountX2, search fields
+	u16(&sub, 0x5a, 0x102, 0xffff)   // end codes
+	u16(&sub, 0)                     // padding
+	u16(&sub, 0x41, 0x100, 0xffff)   // start codes
+	u16(&sub, 0, 0, 1)               // deltas
+	u16(&sub, 0, 4, 0)               // range offsets; the second points past the third to the array
+	u16(&sub, 7, 0, 9)               // glyph ids for 0x100, 0x101, 0x102
+
+	var cmap bytes.Buffer
+	u16(&cmap, 0, 1, 3, 1)
+	binary.Write(&cmap, be, uint32(12))
+	cmap.Write(sub.Bytes())
+
+	var font bytes.Buffer
+	u16(&font, 1, 0, 1, 0, 0, 0) // version, numTables and search fields
+	font.WriteString("cmap")
+	binary.Write(&font, be, uint32(0))
+	binary.Write(&font, be, uint32(12+16))
+	binary.Write(&font, be, uint32(cmap.Len()))
+	font.Write(cmap.Bytes())
+	return font.Bytes()
+}
+
+func TestParseCoverage(t *testing.T) {
+	cov, err := parseCoverage(cmapFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := glyphCoverage{{0x41, 0x5a}, {0x100, 0x100}, {0x102, 0x102}}
+	if !reflect.DeepEqual(cov, want) {
+		t.Errorf("got %v; want %v", cov, want)
+	}
+
+	if _, err := parseCoverage([]byte("not a font")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestFontSpans(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.fontCoverage = map[string]glyphCoverage{
+		"latin": {{0x20, 0x24f}},
+		"greek": {{0x20, 0x7e}, {0x370, 0x3ff}},
+	}
+	s := Styler{Font: "Helvetica", FallbackFonts: "Latin, Greek"}
+
+	var got []string
+	for _, span := range r.fontSpans(s, "café αβγ 日本 ok") {
+		got = append(got, span.style.Font+":"+span.text)
+	}
+	want := []string{"Helvetica:caf", "Latin:é ", "Greek:αβγ ", "Helvetica:日本 ok"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	if spans := r.fontSpans(Styler{Font: "Helvetica"}, "café"); len(spans) != 1 {
+		t.Errorf("got %v without fallbacks", spans)
+	}
+}
+
+func TestFallbackFonts(t *testing.T) {
+	needDejaVu(t)
+	r := NewPdfRenderer("", "", dejaVuDir)
+	r.Pdf.SetCompression(false)
+	if err := r.RegisterFontFamily("DejaVu", "DejaVuSans.ttf", "DejaVuSans-Bold.ttf", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !r.hasGlyph("DejaVu", 'é') || r.hasGlyph("DejaVu", '日') {
+		t.Errorf("got the wrong coverage: %d ranges", len(r.fontCoverage["dejavu"]))
+	}
+	r.SetFallbackFonts("DejaVu")
+
+	md := "# Café\n\nA *naïve* **façade**, [très](http://example.com) bien.\n\n| Größe |\n|---|\n| Ω |\n\n    ∑ code\n"
+	var buf bytes.Buffer
+	if err := r.Process([]byte(md)).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// text in a TrueType font is written as UTF-16
+	for _, text := range []string{"(Caf)", "(\x00\xe9)", "(na)", "(\x00\xef)", "(\x03\xa9)", "(Gr)", "(code)"} {
+		if !bytes.Contains(buf.Bytes(), []byte(text)) {
+			t.Errorf("%q is missing", text)
+		}
+	}
+}
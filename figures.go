@@ -0,0 +1,102 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"strings"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// figure records a captioned image for the list of figures.
+type figure struct {
+	caption string
+	page    int
+	link    int
+}
+
+// plainText collects the text within a node, such as the alt text of an
+// image or the text of a heading, ignoring any formatting.
+func plainText(node *bf.Node) string {
+	var sb strings.Builder
+	node.Walk(func(n *bf.Node, entering bool) bf.WalkStatus {
+		if entering && (n.Type == bf.Text || n.Type == bf.Code) {
+			sb.Write(n.Literal)
+		}
+		return bf.GoToNext
+	})
+	return strings.TrimSpace(sb.String())
+}
+
+// captionOf gives the caption for an image: its title if it has one, or
+// else its alt text. When figures are labelled, the label and the next
+// figure number are put in front, e.g. "Figure 3: Down by the bay".
+func (r *PdfRenderer) captionOf(node *bf.Node) string {
+	text := strings.TrimSpace(string(node.LinkData.Title))
+	if text == "" {
+		text = plainText(node)
+	}
+	if text == "" || r.FigureLabel == "" {
+		return text
+	}
+	return fmt.Sprintf("%s %d: %s", r.FigureLabel, len(r.figures)+1, text)
+}
+
+// addFigure records the position of an image that has just been placed, so
+// that the list of figures can link to it, and writes its caption beneath
+// it using the same alignment as the image.
+func (r *PdfRenderer) addFigure(caption, align string, top float64) {
+	if !r.FigureCaptions || caption == "" {
+		return
+	}
+
+	link := r.Pdf.AddLink()
+	r.Pdf.SetLink(link, top, -1)
+	r.figures = append(r.figures, figure{caption: caption, page: r.Pdf.PageNo(), link: link})
+
+	r.tracer("... Caption", caption)
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	if align == "" {
+		align = "L"
+	}
+
+	r.setStyler(r.Caption)
+	r.Pdf.SetX(lm)
+	r.Pdf.MultiCell(pageW-lm-rm, r.Caption.Size+r.Caption.Spacing, r.visual(caption), "", align, false)
+	r.setStyler(r.cs.peek().textStyle)
+}
+
+// renderListOfFigures adds a page at the end of the document listing each
+// captioned figure with its page number, linked to the figure itself.
+func (r *PdfRenderer) renderListOfFigures() {
+	if !r.ListOfFigures || len(r.figures) == 0 {
+		return
+	}
+
+	r.tracer("List of Figures", fmt.Sprintf("%d figures", len(r.figures)))
+	r.Pdf.SetLeftMargin(r.mleft)
+	r.addPage()
+
+	r.setStyler(r.H1)
+	r.Pdf.CellFormat(0, r.H1.Size+r.H1.Spacing, r.ListOfFiguresTitle, "", 1, "L", false, 0, "")
+	r.Pdf.Ln(r.H1.Spacing)
+
+	r.setStyler(r.Normal)
+	lh := r.Normal.Size + r.Normal.Spacing
+	pageW, _ := r.Pdf.GetPageSize()
+	numW := 3 * r.em
+	for _, f := range r.figures {
+		textW := pageW - r.mleft - r.mright - numW
+		r.Pdf.CellFormat(textW, lh, r.visual(f.caption), "", 0, "L", false, f.link, "")
+		r.Pdf.CellFormat(numW, lh, fmt.Sprintf("%d", f.page), "", 1, "R", false, f.link, "")
+	}
+}
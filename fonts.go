@@ -0,0 +1,135 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// A font family is registered with gofpdf as four faces: regular, bold,
+// italic and bold italic. A face that isn't supplied is stood in for by
+// the nearest one that is, so that emphasis in the markdown never asks
+// gofpdf for a face it doesn't have; the text is then simply not bold or
+// not italic.
+
+// RegisterFontFamily adds a family of TrueType fonts from files. Relative
+// paths are taken from the font directory given to New. Only
+// the regular face is required; the others may be blank.
+func (r *PdfRenderer) RegisterFontFamily(name, regular, bold, italic, boldItalic string) error {
+	return r.registerFontFiles(name, [4]string{regular, bold, italic, boldItalic}, func(file string) ([]byte, error) {
+		if r.fontDir != "" && !filepath.IsAbs(file) {
+			file = filepath.Join(r.fontDir, file)
+		}
+		return ioutil.ReadFile(file)
+	})
+}
+
+// RegisterFontFamilyFS adds a family of TrueType fonts from files in fsys.
+// Only the regular face is required; the others may be blank.
+func (r *PdfRenderer) RegisterFontFamilyFS(fsys fs.FS, name, regular, bold, italic, boldItalic string) error {
+	return r.registerFontFiles(name, [4]string{regular, bold, italic, boldItalic}, func(file string) ([]byte, error) {
+		return fs.ReadFile(fsys, file)
+	})
+}
+
+func (r *PdfRenderer) registerFontFiles(name string, files [4]string, read func(string) ([]byte, error)) error {
+	var faces [4][]byte
+	for i, file := range files {
+		if file == "" {
+			continue
+		}
+		data, err := read(file)
+		if err != nil {
+			return fmt.Errorf("font %s: %w", name, err)
+		}
+		faces[i] = data
+	}
+	return r.RegisterFontFamilyFromBytes(name, faces[0], faces[1], faces[2], faces[3])
+}
+
+// RegisterFontFamilyFromBytes adds a family of TrueType fonts from the
+// contents of their files. Only the regular face is required; the others
+// may be nil.
+func (r *PdfRenderer) RegisterFontFamilyFromBytes(name string, regular, bold, italic, boldItalic []byte) error {
+	if len(regular) == 0 {
+		return fmt.Errorf("font %s: no regular face", name)
+	}
+	if len(boldItalic) == 0 {
+		switch {
+		case len(bold) > 0:
+			boldItalic = bold
+		case len(italic) > 0:
+			boldItalic = italic
+		default:
+			boldItalic = regular
+		}
+	}
+	if len(bold) == 0 {
+		bold = regular
+	}
+	if len(italic) == 0 {
+		italic = regular
+	}
+
+	if cov, err := parseCoverage(regular); err == nil {
+		if r.fontCoverage == nil {
+			r.fontCoverage = make(map[string]glyphCoverage)
+		}
+		r.fontCoverage[strings.ToLower(name)] = cov
+	}
+
+	for _, face := range []struct {
+		style string
+		data  []byte
+	}{
+		{"", regular}, {"B", bold}, {"I", italic}, {"BI", boldItalic},
+	} {
+		r.Pdf.AddUTF8FontFromBytes(name, face.style, face.data)
+	}
+	if r.Pdf.Err() {
+		return fmt.Errorf("font %s: %w", name, r.Pdf.Error())
+	}
+	return nil
+}
+
+// SetFonts changes the fonts of the stylers in one go: body for normal
+// text, links, block quotes, tables and captions, heading for headings and
+// the title and subtitle, and mono for code. A blank name leaves those
+// stylers as they are. The fonts are either standard PDF fonts or families
+// that have been registered, e.g. with RegisterFontFamily. An indentation
+// that followed the old body font, because the theme didn't give one,
+// follows the new one.
+func (r *PdfRenderer) SetFonts(body, heading, mono string) {
+	set := func(font string, stylers ...*Styler) {
+		if font == "" {
+			return
+		}
+		for _, s := range stylers {
+			s.Font = font
+		}
+	}
+	tl := &r.TitleLayout
+	set(body, &r.Normal, &r.Link, &r.Blockquote, &r.THeader, &r.TBody, &r.Caption, &tl.Authors, &tl.Date, &tl.Version)
+	set(heading, &r.H1, &r.H2, &r.H3, &r.H4, &r.H5, &r.H6, &tl.Title, &tl.Subtitle)
+	set(mono, &r.Backtick)
+
+	if body != "" {
+		derived := r.IndentValue == 3*r.em
+		r.setStyler(r.Normal)
+		r.em = r.Pdf.GetStringWidth("m")
+		if derived {
+			r.IndentValue = 3 * r.em
+		}
+	}
+}
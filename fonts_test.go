@@ -0,0 +1,89 @@
+package mdtopdf
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+const dejaVuDir = "/usr/share/fonts/truetype/dejavu"
+
+func needDejaVu(t *testing.T) {
+	if _, err := os.Stat(dejaVuDir + "/DejaVuSans.ttf"); err != nil {
+		t.Skip("DejaVu fonts are not installed")
+	}
+}
+
+func TestRegisterFontFamily(t *testing.T) {
+	needDejaVu(t)
+	r := NewPdfRenderer("", "", dejaVuDir)
+	err := r.RegisterFontFamily("DejaVu", "DejaVuSans.ttf", "DejaVuSans-Bold.ttf", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, style := range []string{"", "B", "I", "BI"} {
+		if r.Pdf.GetFontDesc("DejaVu", style).Ascent == 0 {
+			t.Errorf("style %q not registered", style)
+		}
+	}
+
+	r.SetFonts("DejaVu", "DejaVu", "")
+	if r.Normal.Font != "DejaVu" || r.H2.Font != "DejaVu" || r.TitleLayout.Title.Font != "DejaVu" || r.Backtick.Font != monoFont {
+		t.Errorf("got %+v", r.Theme())
+	}
+
+	md := "# Ünïcödé\n\nSome *emphasis*, **strong** and ***both***, with `code`.\n\n> A quote.\n"
+	if err := r.Process([]byte(md)).Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterFontFamilyFS(t *testing.T) {
+	needDejaVu(t)
+	regular, err := ioutil.ReadFile(dejaVuDir + "/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"fonts/sans.ttf": &fstest.MapFile{Data: regular}}
+
+	r := NewPdfRenderer("", "", "")
+	if err := r.RegisterFontFamilyFS(fsys, "Sans", "fonts/sans.ttf", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.GetFontDesc("Sans", "BI").Ascent == 0 {
+		t.Error("bold italic not registered")
+	}
+	if err := r.RegisterFontFamilyFS(fsys, "Sans", "fonts/sans.ttf", "fonts/missing.ttf", "", ""); err == nil {
+		t.Error("expected an error for a missing face")
+	}
+}
+
+func TestRegisterFontFamilyErrors(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	if err := r.RegisterFontFamilyFromBytes("None", nil, []byte("x"), nil, nil); err == nil {
+		t.Error("expected an error without a regular face")
+	}
+	if err := r.RegisterFontFamily("Missing", "testdata/missing.ttf", "", "", ""); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestSetFontsIndent(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.SetFonts("Courier", "", "")
+	r.setStyler(r.Normal)
+	em := r.Pdf.GetStringWidth("m")
+	if !near(r.em, em) || !near(r.IndentValue, 3*em) {
+		t.Errorf("em %g, indent %g; want them to follow Courier's %g", r.em, r.IndentValue, em)
+	}
+
+	// an indentation from the theme is kept
+	theme := DefaultTheme()
+	theme.Indent = 30
+	r.SetTheme(theme)
+	r.SetFonts("Times", "", "")
+	if r.IndentValue != 30 {
+		t.Errorf("indent %g, want the theme's", r.IndentValue)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Markdown files may start with front matter: YAML between "---" lines
+// (the closing line may also be "..."), or TOML between "+++" lines.
+//
+//	---
+//	title: Markdown to PDF
+//	author: [Cecil New, Rick Beton]
+//	lang: en
+//	---
+
+// splitFrontMatter separates the front matter, if any, from the markdown
+// that follows it. Its format is "yaml", "toml" or blank if there is none.
+func splitFrontMatter(markdown []byte) (format string, front, rest []byte) {
+	text := bytes.TrimPrefix(markdown, []byte("\ufeff"))
+
+	var fence string
+	var closers []string
+	switch {
+	case bytes.HasPrefix(text, []byte("---\n")):
+		format, fence, closers = "yaml", "---\n", []string{"---", "..."}
+	case bytes.HasPrefix(text, []byte("+++\n")):
+		format, fence, closers = "toml", "+++\n", []string{"+++"}
+	default:
+		return "", nil, markdown
+	}
+
+	body := text[len(fence):]
+	for start := 0; start < len(body); {
+		end := bytes.IndexByte(body[start:], '\n')
+		if end < 0 {
+			end = len(body)
+		} else {
+			end += start
+		}
+		line := strings.TrimRight(string(body[start:end]), " \t")
+		for _, c := range closers {
+			if line == c && end < len(body) {
+				return format, body[:start], body[end+1:]
+			} else if line == c {
+				return format, body[:start], nil
+			}
+		}
+		start = end + 1
+	}
+	// no closing line, so it's not front matter after all
+	return "", nil, markdown
+}
+
+// parseFrontMatter decodes front matter in the given format.
+func parseFrontMatter(format string, front []byte) (map[string]interface{}, error) {
+	fm := make(map[string]interface{})
+	var err error
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(front, &fm)
+	case "toml":
+		_, err = toml.Decode(string(front), &fm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("front matter: %w", err)
+	}
+	return fm, nil
+}
+
+// applyFrontMatter uses the keys it knows to set up the document. Keys
+// that aren't known are ignored, but remain available to the caller.
+func (r *PdfRenderer) applyFrontMatter(fm map[string]interface{}) {
+	if s, ok := stringOf(fm["title"]); ok {
+		r.Metadata.Title = s
+	}
+	if s, ok := stringOf(fm["subtitle"]); ok {
+		r.Metadata.Subtitle = s
+	}
+	if list, ok := listOf(fm["author"], ""); ok {
+		r.Metadata.Authors = list
+	}
+	if list, ok := listOf(fm["authors"], ""); ok {
+		r.Metadata.Authors = list
+	}
+	if s, ok := stringOf(fm["date"]); ok {
+		r.Metadata.Date = s
+	}
+	if s, ok := stringOf(fm["version"]); ok {
+		r.Metadata.Version = s
+	}
+	if s, ok := stringOf(fm["subject"]); ok {
+		r.Metadata.Subject = s
+	}
+	if list, ok := listOf(fm["keywords"], ","); ok {
+		r.Metadata.Keywords = list
+	}
+	if s, ok := stringOf(fm["logo"]); ok {
+		r.Metadata.Logo = s
+	}
+	if s, ok := stringOf(fm["lang"]); ok {
+		r.Language = s
+	}
+	if s, ok := stringOf(fm["dir"]); ok {
+		r.Direction = s
+	}
+
+	if b, ok := fm["titlepage"].(bool); ok {
+		r.TitlePage = b
+	}
+
+	switch toc := fm["toc"].(type) {
+	case bool:
+		r.TableOfContents = toc
+	case int:
+		r.TableOfContents, r.TableOfContentsDepth = toc > 0, toc
+	case int64:
+		r.TableOfContents, r.TableOfContentsDepth = toc > 0, int(toc)
+	}
+
+	if s, ok := stringOf(fm["theme"]); ok {
+		if err := r.UseTheme(s); err != nil {
+			r.err = err
+		}
+	}
+
+	if s, ok := stringOf(fm["css"]); ok {
+		if err := r.LoadStylesheet(s); err != nil {
+			r.err = err
+		}
+	}
+
+	paper, hasPaper := stringOf(fm["paper"])
+	orientation, hasOrientation := stringOf(fm["orientation"])
+	if hasPaper || hasOrientation {
+		if !hasPaper {
+			paper = "" // keep the current size
+		}
+		if !hasOrientation {
+			orientation = r.orientation
+		}
+		r.setPageFormat(orientation, paper)
+	}
+}
+
+// stringOf converts a scalar front matter value to a string.
+func stringOf(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return x, true
+	case time.Time:
+		return x.Format("2006-01-02"), true
+	case []interface{}, map[string]interface{}:
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+// listOf converts a front matter list, or a string of items separated by
+// sep, to a list of strings. A blank sep means the string is one item.
+func listOf(v interface{}, sep string) ([]string, bool) {
+	switch x := v.(type) {
+	case []interface{}:
+		var list []string
+		for _, item := range x {
+			if s, ok := stringOf(item); ok {
+				list = append(list, s)
+			}
+		}
+		return list, true
+	case string:
+		if sep == "" {
+			return []string{x}, true
+		}
+		var list []string
+		for _, s := range strings.Split(x, sep) {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		return list, true
+	}
+	return nil, false
+}
@@ -0,0 +1,128 @@
+package mdtopdf
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	cases := []struct {
+		markdown, format, front, rest string
+	}{
+		{"# Title\n", "", "", "# Title\n"},
+		{"---\ntitle: x\n---\n# Title\n", "yaml", "title: x\n", "# Title\n"},
+		{"---\ntitle: x\n...\nBody", "yaml", "title: x\n", "Body"},
+		{"\ufeff+++\ntitle = 'x'\n+++\nBody", "toml", "title = 'x'\n", "Body"},
+		{"---\ntitle: x\n---", "yaml", "title: x\n", ""},
+		{"---\n\nA horizontal rule, not front matter\n", "", "", "---\n\nA horizontal rule, not front matter\n"},
+	}
+
+	for _, c := range cases {
+		format, front, rest := splitFrontMatter([]byte(c.markdown))
+		if format != c.format || string(front) != c.front || string(rest) != c.rest {
+			t.Errorf("%q: got %q %q %q", c.markdown, format, front, rest)
+		}
+	}
+}
+
+func TestProcessFrontMatter(t *testing.T) {
+	yamlDoc := `---
+title: The Title
+author: [A. Writer, B. Writer]
+subject: Testing
+keywords: pdf, markdown
+date: 2021-03-14
+lang: de
+toc: 2
+paper: A5
+orientation: landscape
+status: draft
+---
+# Heading
+`
+	r := NewPdfRenderer("", "", "")
+	r.Process([]byte(yamlDoc))
+
+	want := Metadata{
+		Title:    "The Title",
+		Authors:  []string{"A. Writer", "B. Writer"},
+		Subject:  "Testing",
+		Keywords: []string{"pdf", "markdown"},
+		Date:     "2021-03-14",
+	}
+	if !reflect.DeepEqual(r.Metadata, want) {
+		t.Errorf("got %+v", r.Metadata)
+	}
+	if r.Language != "de" || !r.TableOfContents || r.TableOfContentsDepth != 2 {
+		t.Errorf("got lang %q, toc %v %d", r.Language, r.TableOfContents, r.TableOfContentsDepth)
+	}
+	if r.FrontMatter["status"] != "draft" {
+		t.Errorf("unknown keys should be available; got %v", r.FrontMatter)
+	}
+	if string(r.markdown) != "# Heading\n" {
+		t.Errorf("front matter not removed: %q", r.markdown)
+	}
+	if err := r.Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.PageNo() != 2 {
+		t.Errorf("expected a contents page; got %d pages", r.Pdf.PageNo())
+	}
+	if w, h := r.Pdf.GetPageSize(); w < h || w > 600 {
+		t.Errorf("expected A5 landscape; got %v x %v", w, h)
+	}
+}
+
+func TestProcessTOMLFrontMatter(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Process([]byte("+++\ntitle = \"The Title\"\nauthor = \"Writer, A.\"\ntoc = true\n+++\nBody\n"))
+	if r.Metadata.Title != "The Title" || !reflect.DeepEqual(r.Metadata.Authors, []string{"Writer, A."}) || !r.TableOfContents {
+		t.Errorf("got %+v, toc %v", r.Metadata, r.TableOfContents)
+	}
+}
+
+func TestProcessBadFrontMatter(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	err := r.Process([]byte("---\ntitle: [unclosed\n---\nBody\n")).Output(ioutil.Discard)
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestProcessBadFrontMatterNotKept(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Process([]byte("---\ntitle: [unclosed\n---\nBody\n"))
+	if err := r.Process([]byte("Body\n")).Output(ioutil.Discard); err != nil {
+		t.Errorf("the error was kept for the next document: %v", err)
+	}
+}
+
+func TestPaperKeepsFonts(t *testing.T) {
+	r := dejaVuRenderer(t)
+	err := r.Process([]byte("---\npaper: Letter\n---\n# Heading\n\nשלום and text\n")).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := r.Pdf.GetPageSize(); w != 612 {
+		t.Errorf("expected Letter; got width %v", w)
+	}
+}
+
+func TestPaperKeepsPdf(t *testing.T) {
+	needDejaVu(t)
+	r := NewPdfRenderer("", "", dejaVuDir)
+	pdf := r.Pdf
+	r.Pdf.AddUTF8Font("Direct", "", "DejaVuSans.ttf")
+	r.Normal.Font = "Direct"
+	err := r.Process([]byte("---\norientation: landscape\n---\nBody\n")).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf != pdf {
+		t.Errorf("the document was replaced")
+	}
+	if w, h := r.Pdf.GetPageSize(); w < h {
+		t.Errorf("expected landscape; got %v x %v", w, h)
+	}
+}
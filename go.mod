@@ -3,6 +3,8 @@ module github.com/rickb777/mdtopdf
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/phpdave11/gofpdf v1.4.2
 	github.com/russross/blackfriday/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,251 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hyphenator finds the points at which words may be broken with a hyphen,
+// using Knuth-Liang patterns as used by TeX.
+type Hyphenator struct {
+	// LeftMin and RightMin are the fewest letters that may be left at the
+	// start and end of a word when it is broken. Both default to 2.
+	LeftMin, RightMin int
+
+	patterns   map[string][]byte
+	exceptions map[string][]int
+	maxLen     int
+}
+
+// NewHyphenator reads hyphenation patterns in TeX format, as found in the
+// hyph-utf8 collection. Patterns such as "a1b" and ".ach4" are separated by
+// white space; exceptions are whole words with their hyphens, such as
+// "ta-ble". Comments start with '%', and the \patterns{...} and
+// \hyphenation{...} wrappers are optional.
+func NewHyphenator(rd io.Reader) (*Hyphenator, error) {
+	h := &Hyphenator{
+		LeftMin:    2,
+		RightMin:   2,
+		patterns:   make(map[string][]byte),
+		exceptions: make(map[string][]int),
+	}
+
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '%'); i >= 0 {
+			line = line[:i]
+		}
+		for _, tok := range strings.Fields(line) {
+			switch {
+			case strings.HasPrefix(tok, `\`) || tok == "}":
+				// TeX wrappers
+			case strings.ContainsAny(tok, "0123456789"):
+				if err := h.addPattern(tok); err != nil {
+					return nil, err
+				}
+			default:
+				h.addException(tok)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(h.patterns) == 0 {
+		return nil, fmt.Errorf("no hyphenation patterns found")
+	}
+	return h, nil
+}
+
+// addPattern splits a pattern such as "a1b" into its letters "ab" and the
+// values between them, {0, 1, 0}.
+func (h *Hyphenator) addPattern(pat string) error {
+	var letters []rune
+	values := []byte{0}
+	for _, c := range pat {
+		if c >= '0' && c <= '9' {
+			values[len(values)-1] = byte(c - '0')
+		} else {
+			letters = append(letters, unicode.ToLower(c))
+			values = append(values, 0)
+		}
+	}
+	if len(letters) == 0 {
+		return fmt.Errorf("bad hyphenation pattern %q", pat)
+	}
+	h.patterns[string(letters)] = values
+	if len(letters) > h.maxLen {
+		h.maxLen = len(letters)
+	}
+	return nil
+}
+
+// addException records the hyphen positions of a word, counted in letters.
+func (h *Hyphenator) addException(word string) {
+	var letters []rune
+	var points []int
+	for _, c := range word {
+		if c == '-' {
+			points = append(points, len(letters))
+		} else {
+			letters = append(letters, unicode.ToLower(c))
+		}
+	}
+	h.exceptions[string(letters)] = points
+}
+
+// Hyphenate gives the byte offsets within word at which it may be broken.
+// Each run of letters is hyphenated separately, so punctuation attached to
+// a word, as in "(hyphenation),", is allowed for.
+func (h *Hyphenator) Hyphenate(word string) []int {
+	var offsets []int
+	start := -1
+	for i, c := range word + " " {
+		if unicode.IsLetter(c) {
+			if start < 0 {
+				start = i
+			}
+		} else if start >= 0 {
+			for _, p := range h.points(word[start:i]) {
+				offsets = append(offsets, start+p)
+			}
+			start = -1
+		}
+	}
+	return offsets
+}
+
+// points hyphenates a single run of letters.
+func (h *Hyphenator) points(word string) []int {
+	letters := []rune(strings.ToLower(word))
+	n := len(letters)
+	if n < h.LeftMin+h.RightMin {
+		return nil
+	}
+
+	var allowed []int
+	if ex, ok := h.exceptions[string(letters)]; ok {
+		allowed = ex
+	} else {
+		// values[i] applies to the gap before letter i of ".word."
+		text := append(append([]rune{'.'}, letters...), '.')
+		values := make([]byte, len(text)+1)
+		for i := range text {
+			for j := i + 1; j <= len(text) && j-i <= h.maxLen; j++ {
+				if pv, ok := h.patterns[string(text[i:j])]; ok {
+					for k, v := range pv {
+						if v > values[i+k] {
+							values[i+k] = v
+						}
+					}
+				}
+			}
+		}
+		for i := 1; i < n; i++ {
+			if values[i+1]%2 == 1 {
+				allowed = append(allowed, i)
+			}
+		}
+	}
+
+	// convert letter positions into byte offsets
+	var offsets []int
+	for _, p := range allowed {
+		if p >= h.LeftMin && p <= n-h.RightMin {
+			offsets = append(offsets, byteOffset(word, p))
+		}
+	}
+	return offsets
+}
+
+func byteOffset(s string, runes int) int {
+	i := 0
+	for ; runes > 0 && i < len(s); runes-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
+
+//go:embed hyphenation/*.tex
+var bundledPatterns embed.FS
+
+// bundledHyphenation maps language tags to the bundled pattern files.
+var bundledHyphenation = map[string]string{
+	"en":    "hyphenation/en-us.tex",
+	"en-us": "hyphenation/en-us.tex",
+	"de":    "hyphenation/de-1996.tex",
+}
+
+var hyphenators = struct {
+	sync.Mutex
+	m map[string]*Hyphenator
+}{m: make(map[string]*Hyphenator)}
+
+// RegisterHyphenator makes a hyphenator available for a language, given as
+// a tag such as "nl" or "en-GB". This is how patterns for languages other
+// than the bundled English and German are added, or the bundled ones
+// replaced.
+func RegisterHyphenator(lang string, h *Hyphenator) {
+	hyphenators.Lock()
+	defer hyphenators.Unlock()
+	hyphenators.m[normalizeLanguage(lang)] = h
+}
+
+// HyphenatorFor gives the hyphenator for a language, or nil if there is
+// none. A tag that is not known, such as "de-AT", falls back to its
+// primary language, "de".
+func HyphenatorFor(lang string) *Hyphenator {
+	hyphenators.Lock()
+	defer hyphenators.Unlock()
+
+	for tag := normalizeLanguage(lang); tag != ""; {
+		if h, ok := hyphenators.m[tag]; ok {
+			return h
+		}
+		if file, ok := bundledHyphenation[tag]; ok {
+			h := loadBundledHyphenator(file)
+			hyphenators.m[tag] = h
+			return h
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return nil
+}
+
+func loadBundledHyphenator(file string) *Hyphenator {
+	f, err := bundledPatterns.Open(file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	h, err := NewHyphenator(f)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(lang), "_", "-", -1))
+}
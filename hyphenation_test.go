@@ -0,0 +1,83 @@
+package mdtopdf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hyphenated(h *Hyphenator, word string) string {
+	var parts []string
+	last := 0
+	for _, i := range h.Hyphenate(word) {
+		parts = append(parts, word[last:i])
+		last = i
+	}
+	return strings.Join(append(parts, word[last:]), "-")
+}
+
+func TestNewHyphenator(t *testing.T) {
+	src := `% a comment
+\patterns{
+1ba 1na
+}
+\hyphenation{ ban-a-na }`
+	h, err := NewHyphenator(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.LeftMin, h.RightMin = 1, 1
+
+	cases := map[string]string{
+		"cabana":   "ca-ba-na",
+		"Banana":   "Ban-a-na", // exception, whatever the case
+		"(cabana)": "(ca-ba-na)",
+		"ab":       "ab",
+	}
+	for word, want := range cases {
+		if got := hyphenated(h, word); got != want {
+			t.Errorf("%s: got %s; want %s", word, got, want)
+		}
+	}
+
+	if _, err := NewHyphenator(strings.NewReader("% nothing")); err == nil {
+		t.Errorf("expected an error for an empty pattern file")
+	}
+}
+
+func TestBundledHyphenation(t *testing.T) {
+	cases := []struct{ lang, word, want string }{
+		{"en", "hyphenation", "hy-phen-a-tion"},
+		{"en-GB", "computer", "com-put-er"},
+		{"EN_us", "table", "ta-ble"},
+		{"de", "Silbentrennung", "Sil-ben-tren-nung"},
+		{"de-AT", "Übergröße", "Über-grö-ße"},
+	}
+	for _, c := range cases {
+		h := HyphenatorFor(c.lang)
+		if h == nil {
+			t.Fatalf("no hyphenator for %s", c.lang)
+		}
+		if got := hyphenated(h, c.word); got != c.want {
+			t.Errorf("%s %s: got %s; want %s", c.lang, c.word, got, c.want)
+		}
+	}
+
+	if HyphenatorFor("xx") != nil || HyphenatorFor("") != nil {
+		t.Errorf("expected no hyphenator for unknown languages")
+	}
+}
+
+func TestRegisterHyphenator(t *testing.T) {
+	h, err := NewHyphenator(strings.NewReader("a1a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterHyphenator("x-test", h)
+	if HyphenatorFor("X-Test-Variant") != h {
+		t.Errorf("expected the registered hyphenator")
+	}
+	if got := h.Hyphenate("baab"); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,663 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF decoding for image.DecodeConfig
+	_ "image/jpeg" // register JPEG decoding for image.DecodeConfig
+	_ "image/png"  // register PNG decoding for image.DecodeConfig
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/phpdave11/gofpdf"
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// resolveImagePath turns a markdown image destination into the path used to
+// read the image. Relative destinations are taken to be relative to BaseDir.
+// When ImageFS is in use, the result is a slash-separated fs.FS path.
+func (r *PdfRenderer) resolveImagePath(dest string) string {
+	if r.ImageFS != nil {
+		p := path.Join(filepath.ToSlash(r.BaseDir), dest)
+		return strings.TrimPrefix(path.Clean(p), "/")
+	}
+
+	if r.BaseDir == "" || filepath.IsAbs(dest) {
+		return filepath.Clean(dest)
+	}
+	return filepath.Join(r.BaseDir, dest)
+}
+
+// readImage reads the raw bytes of a local image, using ImageFS if it is set
+// and the operating system's filesystem otherwise.
+func (r *PdfRenderer) readImage(imgPath string) ([]byte, error) {
+	if r.ImageFS != nil {
+		return fs.ReadFile(r.ImageFS, imgPath)
+	}
+	return ioutil.ReadFile(imgPath)
+}
+
+// pdfImage is an image that is ready to be placed on the page.
+type pdfImage interface {
+	// extent gives the natural size of the image, in points.
+	extent() (w, h float64)
+	// draw puts the image on the current page in the given box.
+	draw(r *PdfRenderer, x, y, w, h float64)
+}
+
+// rasterImage is a JPEG, PNG or GIF image registered with gofpdf.
+type rasterImage struct {
+	name string
+	info *gofpdf.ImageInfoType
+}
+
+func (i rasterImage) extent() (w, h float64) {
+	return i.info.Extent()
+}
+
+func (i rasterImage) draw(r *PdfRenderer, x, y, w, h float64) {
+	r.Pdf.ImageOptions(i.name, x, y, w, h, false,
+		gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+}
+
+// loadImage locates the image for a markdown destination and prepares it
+// for drawing. Each image is only loaded once however often it is used.
+func (r *PdfRenderer) loadImage(dest string) (pdfImage, error) {
+	name := dest
+	if strings.HasPrefix(strings.ToLower(dest), "data:") {
+		// register data URLs under a digest rather than the (possibly
+		// very long) URL itself
+		sum := sha256.Sum256([]byte(dest))
+		name = "data:" + hex.EncodeToString(sum[:])
+	} else if !isImageURL(dest) {
+		name = r.resolveImagePath(dest)
+	}
+
+	if img, exists := r.images[name]; exists {
+		return img, nil
+	}
+
+	var data []byte
+	var err error
+	if !isImageURL(dest) {
+		// following changes suggested by @sirnewton01, issue #6: a file
+		// that doesn't exist is reported rather than handed to gofpdf
+		data, err = r.readImage(name)
+	} else if r.ImageLoader == nil {
+		err = fmt.Errorf("%.40s: no ImageLoader is configured", dest)
+	} else {
+		data, err = r.ImageLoader.LoadImage(dest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := r.decodeImage(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.images == nil {
+		r.images = make(map[string]pdfImage)
+	}
+	r.images[name] = img
+	return img, nil
+}
+
+// decodeImage turns image data into a pdfImage. SVG documents are parsed
+// for drawing as vector graphics; other images are registered with the PDF.
+// The data is checked first so that unreadable images are reported here.
+// gofpdf rejects some images that decode, such as 16-bit or interlaced
+// PNGs; its error is cleared so that rendering can go on without them.
+func (r *PdfRenderer) decodeImage(name string, data []byte) (pdfImage, error) {
+	if isSVG(name, data) {
+		svg, err := parseSVG(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return svg, nil
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	info := r.Pdf.RegisterImageOptionsReader(name,
+		gofpdf.ImageOptions{ImageType: format, ReadDpi: true},
+		bytes.NewReader(data))
+	if r.Pdf.Err() {
+		err := r.Pdf.Error()
+		r.Pdf.ClearError()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return rasterImage{name: name, info: info}, nil
+}
+
+// imageHints holds the size and alignment requested for an image in the
+// markdown source, either as a "=WxH" suffix on the destination or as a
+// "{width=50% align=center}" attribute list after the image.
+type imageHints struct {
+	// width and height are in points; zero if not given.
+	width, height float64
+	// widthPct and heightPct are fractions of the content width and of
+	// the usable page height; zero if not given.
+	widthPct, heightPct float64
+	// align is "L", "C" or "R"; blank if not given.
+	align string
+}
+
+// splitSizeSuffix separates a destination such as "pic.png =300x200" into the
+// path and its size hints. Sizes without units are pixels.
+func splitSizeSuffix(dest string) (string, imageHints) {
+	var hints imageHints
+	i := strings.LastIndex(dest, " =")
+	if i < 0 {
+		return dest, hints
+	}
+
+	size := dest[i+2:]
+	x := strings.IndexByte(size, 'x')
+	if x < 0 {
+		return dest, hints
+	}
+
+	if !hints.setLength("width", size[:x]) || !hints.setLength("height", size[x+1:]) {
+		return dest, imageHints{}
+	}
+	return strings.TrimSpace(dest[:i]), hints
+}
+
+// parseImageAttributes reads an attribute list such as
+// "{width=50% align=center}" from the start of s. It returns the hints and
+// whatever text follows the closing brace.
+func parseImageAttributes(s string) (imageHints, string, bool) {
+	var hints imageHints
+	if !strings.HasPrefix(s, "{") {
+		return hints, s, false
+	}
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return hints, s, false
+	}
+
+	for _, attr := range strings.Fields(s[1:end]) {
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(kv[0])
+		value := strings.Trim(kv[1], `"'`)
+		switch key {
+		case "width", "height":
+			hints.setLength(key, value)
+		case "align":
+			hints.align = alignOf(value)
+		}
+	}
+	return hints, s[end+1:], true
+}
+
+// setLength parses a CSS-like length such as "300", "300px", "5cm" or "50%"
+// into the width or height hint. An empty value is accepted and ignored.
+func (h *imageHints) setLength(which, value string) bool {
+	if value == "" {
+		return true
+	}
+
+	pts, pct, ok := parseLength(value)
+	if !ok {
+		return false
+	}
+	if which == "width" {
+		h.width, h.widthPct = pts, pct
+	} else {
+		h.height, h.heightPct = pts, pct
+	}
+	return true
+}
+
+// merge overlays any hints given in o onto h.
+func (h imageHints) merge(o imageHints) imageHints {
+	if o.width != 0 || o.widthPct != 0 {
+		h.width, h.widthPct = o.width, o.widthPct
+	}
+	if o.height != 0 || o.heightPct != 0 {
+		h.height, h.heightPct = o.height, o.heightPct
+	}
+	if o.align != "" {
+		h.align = o.align
+	}
+	return h
+}
+
+// parseLength converts a length to points, or to a fraction if it is a
+// percentage. Plain numbers are pixels at 96 per inch, as in HTML.
+func parseLength(s string) (pts, pct float64, ok bool) {
+	units := map[string]float64{
+		"px": 0.75, "pt": 1, "pc": 12, "in": 72, "cm": 72 / 2.54, "mm": 72 / 25.4, "%": 0,
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	factor := 0.75
+	for suffix, f := range units {
+		if strings.HasSuffix(s, suffix) {
+			s, factor = strings.TrimSuffix(s, suffix), f
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, 0, false
+	}
+	if factor == 0 {
+		return 0, v / 100, true
+	}
+	return v * factor, 0, true
+}
+
+// alignOf converts an alignment name to the gofpdf style "L", "C" or "R".
+func alignOf(s string) string {
+	switch strings.ToLower(s) {
+	case "l", "left":
+		return "L"
+	case "c", "center", "centre", "middle":
+		return "C"
+	case "r", "right":
+		return "R"
+	}
+	return ""
+}
+
+// placeImage draws a registered image as a block on its own line, scaled
+// down if necessary to fit within the content width and the page height.
+// If it doesn't fit in the space left on the current page, it starts a
+// new page. If link or url is set, the image is clickable. It returns the
+// alignment used and the y position of the top of the image.
+func (r *PdfRenderer) placeImage(img pdfImage, hints imageHints, url string) (align string, top float64) {
+	lm, tm, rm, _ := r.Pdf.GetMargins()
+	pageW, pageH := r.Pdf.GetPageSize()
+	_, bm := r.Pdf.GetAutoPageBreak()
+
+	contentW := pageW - lm - rm
+	usableH := pageH - tm - bm
+	w, h := r.imageExtent(img, hints, contentW, usableH)
+
+	// start a fresh line unless already at the start of one
+	if r.Pdf.GetX() > lm+0.5 {
+		r.cr()
+	}
+
+	if r.Pdf.GetY()+h > pageH-bm {
+		r.tracer("... Image", "moving to the next page")
+		r.addPage()
+	}
+
+	align = hints.align
+	if align == "" {
+		align = alignOf(r.ImageAlign)
+	}
+	x := lm
+	switch align {
+	case "C":
+		x += (contentW - w) / 2
+	case "R":
+		x += contentW - w
+	}
+
+	y := r.Pdf.GetY()
+	r.tracer("... Image", fmt.Sprintf("x=%.1f y=%.1f w=%.1f h=%.1f", x, y, w, h))
+	img.draw(r, x, y, w, h)
+	if url != "" {
+		r.Pdf.LinkString(x, y, w, h, url)
+	}
+	r.Pdf.SetXY(lm, y+h)
+	return align, y
+}
+
+// imageExtent works out the size of an image from its natural size and the
+// hints, preserving the aspect ratio unless both dimensions are given and
+// then shrinking it to fit within maxW by maxH.
+func (r *PdfRenderer) imageExtent(img pdfImage, hints imageHints, maxW, maxH float64) (w, h float64) {
+	natW, natH := img.extent()
+
+	w, h = hints.width, hints.height
+	if hints.widthPct > 0 {
+		w = hints.widthPct * maxW
+	}
+	if hints.heightPct > 0 {
+		h = hints.heightPct * maxH
+	}
+
+	switch {
+	case w == 0 && h == 0:
+		w, h = natW, natH
+	case h == 0:
+		h = w * natH / natW
+	case w == 0:
+		w = h * natW / natH
+	}
+
+	limit := maxW
+	if r.ImageMaxWidth > 0 && r.ImageMaxWidth < 1 {
+		limit = r.ImageMaxWidth * maxW
+	}
+	if w > limit {
+		h, w = h*limit/w, limit
+	}
+	if h > maxH {
+		w, h = w*maxH/h, maxH
+	}
+	return w, h
+}
+
+// MissingImagePolicy chooses what happens when an image can't be loaded.
+type MissingImagePolicy int
+
+const (
+	// ImagePlaceholder draws a bordered box containing the image's alt
+	// text and path in place of the image. This is the default.
+	ImagePlaceholder MissingImagePolicy = iota
+	// ImageSkip leaves the image out.
+	ImageSkip
+	// ImageFatal stops rendering; ToFile or Output then return the error.
+	ImageFatal
+)
+
+// ImageWarning describes an image that could not be loaded.
+type ImageWarning struct {
+	Destination string
+	AltText     string
+	Err         error
+}
+
+func (w ImageWarning) Error() string {
+	return fmt.Sprintf("image %q: %v", w.Destination, w.Err)
+}
+
+func (w ImageWarning) Unwrap() error {
+	return w.Err
+}
+
+// ImageWarnings returns the images that could not be loaded during
+// rendering, in document order.
+func (r *PdfRenderer) ImageWarnings() []ImageWarning {
+	return r.imageWarnings
+}
+
+// missingImage records an image that couldn't be loaded and deals with it
+// according to the MissingImage policy. It returns false if rendering must
+// stop.
+func (r *PdfRenderer) missingImage(dest, alt string, hints imageHints, inline bool, err error) bool {
+	warning := ImageWarning{Destination: dest, AltText: alt, Err: err}
+	r.imageWarnings = append(r.imageWarnings, warning)
+
+	switch r.MissingImage {
+	case ImageFatal:
+		r.err = warning
+		return false
+	case ImagePlaceholder:
+		if inline {
+			r.writeInlinePlaceholder(dest, alt)
+		} else {
+			r.placePlaceholder(dest, alt, hints)
+		}
+	}
+	return true
+}
+
+// writeInlinePlaceholder draws a missing inline image as a bordered box in
+// the flow of the text, the height of the line, holding its alt text or
+// else its path.
+func (r *PdfRenderer) writeInlinePlaceholder(dest, alt string) {
+	text := alt
+	if text == "" {
+		text = dest
+	}
+	s := r.cs.peek().textStyle
+	r.setStyler(r.Caption)
+	w := r.Pdf.GetStringWidth(r.visual(text)) + r.em
+	r.setStyler(s)
+
+	url := ""
+	if c := r.cs.peek(); c.containerType == bf.Link {
+		url = c.destination
+	}
+	r.placeInlineImage(placeholderImage{text: text, w: w, h: s.Size + s.Spacing}, imageHints{}, url)
+}
+
+// placeholderImage is drawn in place of a missing inline image.
+type placeholderImage struct {
+	text string
+	w, h float64
+}
+
+func (p placeholderImage) extent() (w, h float64) {
+	return p.w, p.h
+}
+
+func (p placeholderImage) draw(r *PdfRenderer, x, y, w, h float64) {
+	r.setStyler(r.Caption)
+	r.setPlaceholderColors()
+	r.Pdf.SetXY(x, y)
+	r.Pdf.CellFormat(w, h, r.visual(p.text), "1", 0, "C", true, 0, "")
+	r.setStyler(r.cs.peek().textStyle)
+}
+
+// setPlaceholderColors sets the colours of the box drawn for a missing
+// image.
+func (r *PdfRenderer) setPlaceholderColors() {
+	fill, draw := r.PlaceholderFill, r.PlaceholderColor
+	r.Pdf.SetFillColor(fill.Red, fill.Green, fill.Blue)
+	r.Pdf.SetDrawColor(draw.Red, draw.Green, draw.Blue)
+	r.Pdf.SetLineWidth(0.5)
+}
+
+// placePlaceholder draws a bordered box, as wide as the image was asked to be
+// or else half the content width, containing the alt text and the path.
+func (r *PdfRenderer) placePlaceholder(dest, alt string, hints imageHints) {
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	contentW := pageW - lm - rm
+
+	w := hints.width
+	if hints.widthPct > 0 {
+		w = hints.widthPct * contentW
+	}
+	if w == 0 {
+		w = contentW / 2
+	}
+	if w > contentW {
+		w = contentW
+	}
+
+	if r.Pdf.GetX() > lm+0.5 {
+		r.cr()
+	}
+
+	align := hints.align
+	if align == "" {
+		align = alignOf(r.ImageAlign)
+	}
+	x := lm
+	switch align {
+	case "C":
+		x += (contentW - w) / 2
+	case "R":
+		x += contentW - w
+	}
+
+	text := dest
+	if alt != "" {
+		text = alt + "\n" + dest
+	}
+
+	r.tracer("... Image placeholder", fmt.Sprintf("x=%.1f w=%.1f", x, w))
+	r.setStyler(r.Caption)
+	r.setPlaceholderColors()
+	r.Pdf.SetX(x)
+	r.Pdf.MultiCell(w, r.Caption.Size+r.Caption.Spacing, r.visual(text), "1", "C", true)
+	r.Pdf.SetX(lm)
+	r.setStyler(r.cs.peek().textStyle)
+}
+
+// isStandaloneImage tests whether an image, or a link containing only the
+// image, is a block rather than part of the text: whether it is alone on
+// its line of the paragraph, apart from whitespace.
+func isStandaloneImage(node *bf.Node) bool {
+	n := node
+	if n.Parent != nil && n.Parent.Type == bf.Link && onlyChild(n) {
+		n = n.Parent
+	}
+	return n.Parent != nil && n.Parent.Type == bf.Paragraph && lineEnds(n.Prev, true) && lineEnds(n.Next, false)
+}
+
+// onlyChild tests whether a node's siblings are all blank text or breaks.
+func onlyChild(n *bf.Node) bool {
+	return blankTo(n.Prev, true) && blankTo(n.Next, false)
+}
+
+// lineEnds tests whether the line ends between a node and its sibling sib,
+// which is before it if back is set: whether there is only whitespace
+// before the line break, or the end of the paragraph.
+func lineEnds(sib *bf.Node, back bool) bool {
+	for ; sib != nil; sib = sibling(sib, back) {
+		switch sib.Type {
+		case bf.Softbreak, bf.Hardbreak:
+			return true
+		case bf.Text:
+			text := literal(sib)
+			if back {
+				text = strings.TrimRight(text, " \t")
+			} else {
+				text = strings.TrimLeft(text, " \t")
+			}
+			if text == "" {
+				continue
+			}
+			if back {
+				return strings.HasSuffix(text, "\n")
+			}
+			return strings.HasPrefix(text, "\n")
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// blankTo tests whether the siblings from sib onwards, or back from it,
+// are all blank text or breaks.
+func blankTo(sib *bf.Node, back bool) bool {
+	for ; sib != nil; sib = sibling(sib, back) {
+		switch {
+		case sib.Type == bf.Text && strings.TrimSpace(literal(sib)) == "":
+		case sib.Type == bf.Softbreak || sib.Type == bf.Hardbreak:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// literal gives the text of a Text node, leaving out the {…} attributes of
+// an image just before it.
+func literal(n *bf.Node) string {
+	s := string(n.Literal)
+	if n.Prev != nil && n.Prev.Type == bf.Image {
+		if _, rest, ok := parseImageAttributes(s); ok {
+			return rest
+		}
+	}
+	return s
+}
+
+// followsBlockImage tests whether a Text node comes straight after an
+// image, or a link around one, that is a block of its own, so that the
+// whitespace ending the image's line is not written.
+func followsBlockImage(n *bf.Node) bool {
+	prev := n.Prev
+	if prev != nil && prev.Type == bf.Link {
+		for c := prev.FirstChild; c != nil; c = c.Next {
+			if c.Type == bf.Image {
+				prev = c
+				break
+			}
+		}
+	}
+	return prev != nil && prev.Type == bf.Image && isStandaloneImage(prev)
+}
+
+func sibling(n *bf.Node, back bool) *bf.Node {
+	if back {
+		return n.Prev
+	}
+	return n.Next
+}
+
+// placeInlineImage draws an image in the flow of the text. Unless the
+// markdown gives its size, it is scaled to the height of the current line.
+// It wraps to the next line if it doesn't fit on the current one.
+func (r *PdfRenderer) placeInlineImage(img pdfImage, hints imageHints, url string) {
+	s := r.cs.peek().textStyle
+	lh := s.Size + s.Spacing
+	lm, tm, rm, _ := r.Pdf.GetMargins()
+	pageW, pageH := r.Pdf.GetPageSize()
+	_, bm := r.Pdf.GetAutoPageBreak()
+	contentW := pageW - lm - rm
+
+	var w, h float64
+	if hints.width == 0 && hints.height == 0 && hints.widthPct == 0 && hints.heightPct == 0 {
+		natW, natH := img.extent()
+		w, h = natW*lh/natH, lh
+		if w > contentW {
+			w, h = contentW, natH*contentW/natW
+		}
+	} else {
+		w, h = r.imageExtent(img, hints, contentW, pageH-tm-bm)
+	}
+
+	if r.layout != nil {
+		r.addRun(textRun{img: img, w: w, h: h, url: url})
+		return
+	}
+
+	// an image taller than the line starts a new line, and the text
+	// carries on beside the bottom of the image
+	x, y := r.Pdf.GetXY()
+	if (x+w > pageW-rm || h > lh) && x > lm+0.5 {
+		r.Pdf.Ln(lh)
+		x, y = r.Pdf.GetXY()
+	}
+	if y+h > pageH-bm {
+		r.addPage()
+		x, y = r.Pdf.GetXY()
+	}
+
+	r.tracer("... Inline image", fmt.Sprintf("x=%.1f y=%.1f w=%.1f h=%.1f", x, y, w, h))
+	img.draw(r, x, y, w, h)
+	if url != "" {
+		r.Pdf.LinkString(x, y, w, h, url)
+	}
+	if h > lh {
+		y += h - lh
+	}
+	r.Pdf.SetXY(x+w, y)
+}
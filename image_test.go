@@ -0,0 +1,335 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+func TestSplitSizeSuffix(t *testing.T) {
+	cases := []struct {
+		dest, path string
+		w, h, wPct float64
+	}{
+		{"a.png", "a.png", 0, 0, 0},
+		{"a.png =300x", "a.png", 225, 0, 0},
+		{"a.png =x96", "a.png", 0, 72, 0},
+		{"a.png =50%x", "a.png", 0, 0, 0.5},
+		{"my pic.png =2inx1in", "my pic.png", 144, 72, 0},
+		{"a.png =wide", "a.png =wide", 0, 0, 0},
+	}
+
+	for _, c := range cases {
+		p, h := splitSizeSuffix(c.dest)
+		if p != c.path || h.width != c.w || h.height != c.h || h.widthPct != c.wPct {
+			t.Errorf("%q: got %q %+v", c.dest, p, h)
+		}
+	}
+}
+
+func TestParseImageAttributes(t *testing.T) {
+	h, rest, ok := parseImageAttributes(`{width=50% height="3cm" align=center} and more`)
+	if !ok || rest != " and more" {
+		t.Fatalf("got %v %q", ok, rest)
+	}
+	if h.widthPct != 0.5 || math.Abs(h.height-85.04) > 0.01 || h.align != "C" {
+		t.Errorf("got %+v", h)
+	}
+
+	_, rest, ok = parseImageAttributes("no attributes")
+	if ok || rest != "no attributes" {
+		t.Errorf("got %v %q", ok, rest)
+	}
+}
+
+func TestImagesFitThePage(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.ImageAlign = "center"
+	md := "![bay](image/bay.jpg)\n\n![half](image/bay.jpg){width=50%}\n"
+	err := r.Process([]byte(md)).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := rasterImage{info: r.Pdf.GetImageInfo("image/bay.jpg")}
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pw, ph := r.Pdf.GetPageSize()
+	_, bm := r.Pdf.GetAutoPageBreak()
+
+	w, h := r.imageExtent(img, imageHints{}, pw-lm-rm, ph-bm)
+	if w > pw-lm-rm+0.01 || h > ph-bm+0.01 {
+		t.Errorf("image %vx%v does not fit the page", w, h)
+	}
+
+	w2, _ := r.imageExtent(img, imageHints{widthPct: 0.5}, 400, 1000)
+	if w2 != 200 {
+		t.Errorf("got width %v; want 200", w2)
+	}
+
+	r.ImageMaxWidth = 0.25
+	w3, _ := r.imageExtent(img, imageHints{}, 400, 1000)
+	if w3 != 100 {
+		t.Errorf("got width %v; want 100", w3)
+	}
+}
+
+func TestFigureCaptions(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.FigureCaptions = true
+	r.FigureLabel = "Figure"
+	r.ListOfFigures = true
+	md := `![The bay](image/bay.jpg)
+
+![gopher](image/hiking.png "Hiking gopher")
+
+![](image/fpdf.png)
+
+![missing](image/none.png "Not counted")
+`
+	err := r.Process([]byte(md)).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Figure 1: The bay", "Figure 2: Hiking gopher"}
+	if len(r.figures) != len(want) {
+		t.Fatalf("got %d figures; want %d", len(r.figures), len(want))
+	}
+	for i, f := range r.figures {
+		if f.caption != want[i] || f.page != 1 {
+			t.Errorf("figure %d: got %+v", i+1, f)
+		}
+	}
+	if r.Pdf.PageNo() != 2 {
+		t.Errorf("expected the list of figures on page 2; got %d pages", r.Pdf.PageNo())
+	}
+}
+
+func TestMissingImages(t *testing.T) {
+	md := "Before\n\n![A lost picture](image/none.png)\n\nAfter\n"
+
+	r := NewPdfRenderer("", "", "")
+	err := r.Process([]byte(md)).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := r.ImageWarnings()
+	if len(warnings) != 1 || warnings[0].Destination != "image/none.png" || warnings[0].AltText != "A lost picture" {
+		t.Errorf("got %+v", warnings)
+	}
+
+	r = NewPdfRenderer("", "", "")
+	r.MissingImage = ImageFatal
+	err = r.Process([]byte(md)).Output(ioutil.Discard)
+	var warning ImageWarning
+	if !errors.As(err, &warning) || !os.IsNotExist(errors.Unwrap(err)) {
+		t.Errorf("expected a fatal ImageWarning; got %v", err)
+	}
+}
+
+func TestLinkedImages(t *testing.T) {
+	md := `[![badge](image/fpdf.png)](https://example.com/badge)
+
+[![svg](image/diagram.svg)](https://example.com/diagram)
+`
+	r := NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	buf := &bytes.Buffer{}
+	err := r.Process([]byte(md)).Output(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf := buf.String()
+	if !strings.Contains(pdf, "/URI (https://example.com/badge)") {
+		t.Errorf("external image link is missing")
+	}
+	if strings.Count(pdf, "/Subtype /Link") != 2 {
+		t.Errorf("expected two links; got %d", strings.Count(pdf, "/Subtype /Link"))
+	}
+}
+
+func TestInlineImages(t *testing.T) {
+	md := `Build status ![ok](image/fpdf.png) and a badge [![b](image/hiking.png)](https://example.com).
+
+![standalone](image/bay.jpg)
+
+[![linked standalone](image/hiking.png)](https://example.com)
+
+On its own line:
+![own line](image/bay.jpg)  
+and some text after it, then ![inline](image/fpdf.png)
+at the end of a line.
+`
+	doc := bf.New(bf.WithExtensions(bf.CommonExtensions)).Parse([]byte(md))
+	var standalone []bool
+	doc.Walk(func(n *bf.Node, entering bool) bf.WalkStatus {
+		if entering && n.Type == bf.Image {
+			standalone = append(standalone, isStandaloneImage(n))
+		}
+		return bf.GoToNext
+	})
+	want := []bool{false, false, true, true, true, false}
+	for i := range want {
+		if standalone[i] != want[i] {
+			t.Errorf("image %d: got standalone=%v", i+1, standalone[i])
+		}
+	}
+
+	r := NewPdfRenderer("", "", "")
+	r.FigureCaptions = true
+	err := r.Process([]byte(md)).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.figures) != 3 {
+		t.Errorf("only standalone images should be figures; got %d", len(r.figures))
+	}
+}
+
+func TestImageWithinJustifiedParagraph(t *testing.T) {
+	var trace bytes.Buffer
+	r, err := New(WithTracer(&trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Normal.Align = AlignJustify
+	md := "Text before the image\n![own line](image/fpdf.png)\ntext after it.\n"
+	if err := r.Process([]byte(md)).Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	log := trace.String()
+	before := strings.Index(log, "[... Layout] 4 words")
+	image := strings.Index(log, "[... Image]")
+	after := strings.Index(log, "[... Layout] 3 words")
+	if before < 0 || image < before || after < image {
+		t.Errorf("the text wasn't written around the image:\n%s", log)
+	}
+}
+
+func TestMissingInlineImages(t *testing.T) {
+	md := "Status ![unknown](image/none.png) here\n"
+
+	r := NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	var buf bytes.Buffer
+	if err := r.Process([]byte(md)).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.ImageWarnings()) != 1 {
+		t.Errorf("got %+v", r.ImageWarnings())
+	}
+	pdf := buf.String()
+	if !strings.Contains(pdf, "(unknown)Tj") || strings.Contains(pdf, "[unknown]") {
+		t.Errorf("expected the alt text in a box")
+	}
+	if !strings.Contains(pdf, " re B") {
+		t.Errorf("expected a bordered placeholder")
+	}
+
+	r = NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	r.MissingImage = ImageSkip
+	buf.Reset()
+	if err := r.Process([]byte(md)).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "unknown") {
+		t.Errorf("a skipped image should leave nothing")
+	}
+}
+
+func TestUnsupportedImages(t *testing.T) {
+	// gofpdf doesn't support 16-bit PNGs, though Go decodes them
+	var deep bytes.Buffer
+	if err := png.Encode(&deep, image.NewGray16(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"deep.png": {Data: deep.Bytes()}, "1f680.png": {Data: deep.Bytes()}}
+	md := "Before\n\n![Too deep](deep.png)\n\nAfter ![inline](deep.png) text\n"
+
+	r := NewPdfRenderer("", "", "")
+	r.ImageFS = fsys
+	if err := r.Process([]byte(md)).Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	warnings := r.ImageWarnings()
+	if len(warnings) != 2 || !strings.Contains(warnings[0].Error(), "16-bit") {
+		t.Errorf("got %+v", warnings)
+	}
+
+	r = NewPdfRenderer("", "", "")
+	r.ImageFS = fsys
+	r.MissingImage = ImageFatal
+	err := r.Process([]byte(md)).Output(ioutil.Discard)
+	var warning ImageWarning
+	if !errors.As(err, &warning) || warning.Destination != "deep.png" {
+		t.Errorf("expected a fatal ImageWarning; got %v", err)
+	}
+
+	// the background and emoji share the decoding
+	r = NewPdfRenderer("", "", "")
+	r.ImageFS, r.EmojiImages = fsys, fsys
+	r.BackgroundImage = "deep.png"
+	if err := r.Process([]byte("Launch 🚀 now\n")).Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.ImageWarnings()) != 1 {
+		t.Errorf("got %+v", r.ImageWarnings())
+	}
+}
+
+func TestImageLeavesASTAlone(t *testing.T) {
+	md := "Text ![a](image/fpdf.png){width=20} inline.\n\n![b](image/fpdf.png =50x){align=center}\nafter the block.\n"
+	doc := bf.New(bf.WithExtensions(bf.CommonExtensions)).Parse([]byte(md))
+	texts := func() []string {
+		var got []string
+		doc.Walk(func(n *bf.Node, entering bool) bf.WalkStatus {
+			if n.Type == bf.Text {
+				got = append(got, string(n.Literal))
+			}
+			return bf.GoToNext
+		})
+		return got
+	}
+	want := texts()
+
+	var outputs [][]byte
+	for i := 0; i < 2; i++ {
+		r := NewPdfRenderer("", "", "")
+		r.Pdf.SetCompression(false)
+		r.RenderHeader(ioutil.Discard, doc)
+		doc.Walk(func(n *bf.Node, entering bool) bf.WalkStatus {
+			return r.RenderNode(ioutil.Discard, n, entering)
+		})
+		r.RenderFooter(ioutil.Discard, doc)
+		var buf bytes.Buffer
+		if err := r.Pdf.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, buf.Bytes())
+	}
+
+	if got := texts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the text nodes became %q; want %q", got, want)
+	}
+	if bytes.Contains(outputs[1], []byte("width=")) || bytes.Contains(outputs[1], []byte("align=")) {
+		t.Errorf("the attributes were written")
+	}
+	if !bytes.Contains(outputs[1], []byte("(after the block.)")) {
+		t.Errorf("the text after the block image was not written on its own")
+	}
+	if len(outputs[0]) != len(outputs[1]) {
+		t.Errorf("the second render differs: %d bytes, then %d", len(outputs[0]), len(outputs[1]))
+	}
+}
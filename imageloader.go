@@ -0,0 +1,199 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImageLoader obtains images that are not local files, i.e. those whose
+// markdown destination is an http, https or data URL.
+type ImageLoader interface {
+	// LoadImage returns the raw bytes of the image at the given URL.
+	LoadImage(url string) ([]byte, error)
+}
+
+// DefaultImageLoader is the ImageLoader used unless another is provided.
+// It decodes data URLs itself and fetches http(s) images over the network.
+type DefaultImageLoader struct {
+	// Client is used for http(s) requests; its Timeout limits how long
+	// each fetch may take.
+	Client *http.Client
+
+	// MaxSize is the largest image, in bytes, that will be accepted,
+	// whether it is fetched or read from the cache. Zero means no limit.
+	MaxSize int64
+
+	// CacheDir, if not blank, is a directory in which fetched images are
+	// kept so that later runs do not need to fetch them again.
+	CacheDir string
+}
+
+// Default limits used by NewImageLoader.
+const (
+	DefaultImageTimeout = 30 * time.Second
+	DefaultImageMaxSize = 10 << 20
+)
+
+// NewImageLoader creates a DefaultImageLoader with the default timeout and
+// size limit, and no cache.
+func NewImageLoader() *DefaultImageLoader {
+	return &DefaultImageLoader{
+		Client:  &http.Client{Timeout: DefaultImageTimeout},
+		MaxSize: DefaultImageMaxSize,
+	}
+}
+
+// isImageURL tests whether an image destination should be obtained via an
+// ImageLoader rather than read as a local file.
+func isImageURL(dest string) bool {
+	lower := strings.ToLower(dest)
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "data:")
+}
+
+// LoadImage implements ImageLoader.
+func (l *DefaultImageLoader) LoadImage(u string) ([]byte, error) {
+	if strings.HasPrefix(strings.ToLower(u), "data:") {
+		return decodeDataURL(u)
+	}
+
+	if l.CacheDir != "" {
+		if f, err := os.Open(l.cacheFile(u)); err == nil {
+			defer f.Close()
+			return l.readAll(u, f)
+		}
+	}
+
+	data, err := l.fetch(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.CacheDir != "" {
+		l.store(u, data)
+	}
+	return data, nil
+}
+
+func (l *DefaultImageLoader) fetch(u string) ([]byte, error) {
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+
+	if l.MaxSize > 0 && resp.ContentLength > l.MaxSize {
+		return nil, fmt.Errorf("%s: image is %d bytes; the limit is %d", u, resp.ContentLength, l.MaxSize)
+	}
+
+	return l.readAll(u, resp.Body)
+}
+
+// readAll reads an image, fetched or cached, failing if it is larger than
+// MaxSize; the size a server declares can be missing or wrong.
+func (l *DefaultImageLoader) readAll(u string, rd io.Reader) ([]byte, error) {
+	if l.MaxSize > 0 {
+		rd = io.LimitReader(rd, l.MaxSize+1)
+	}
+
+	data, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", u, err)
+	}
+
+	if l.MaxSize > 0 && int64(len(data)) > l.MaxSize {
+		return nil, fmt.Errorf("%s: image exceeds the limit of %d bytes", u, l.MaxSize)
+	}
+	return data, nil
+}
+
+func (l *DefaultImageLoader) cacheFile(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(l.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+// store writes an image to the cache. Failures are ignored because the cache
+// is only an optimisation.
+func (l *DefaultImageLoader) store(u string, data []byte) {
+	if os.MkdirAll(l.CacheDir, 0755) != nil {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(l.CacheDir, "fetch-*")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.cacheFile(u))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// decodeDataURL extracts the content of a URL such as
+// "data:image/png;base64,iVBORw0KGgo...".
+func decodeDataURL(u string) ([]byte, error) {
+	comma := strings.IndexByte(u, ',')
+	if comma < 0 {
+		return nil, fmt.Errorf("malformed data URL")
+	}
+
+	meta, content := u[len("data:"):comma], u[comma+1:]
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		content = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+				return -1
+			}
+			return r
+		}, content)
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(content, "="))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("data URL: %w", err)
+		}
+		return data, nil
+	}
+
+	s, err := url.PathUnescape(content)
+	if err != nil {
+		return nil, fmt.Errorf("data URL: %w", err)
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,126 @@
+package mdtopdf
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImageLoaderFetchesAndCaches(t *testing.T) {
+	png, err := ioutil.ReadFile("image/fpdf.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hits++
+		switch req.URL.Path {
+		case "/fpdf.png":
+			w.Write(png)
+		case "/chunked.png":
+			// without a Content-Length
+			w.Write(png[:10])
+			w.(http.Flusher).Flush()
+			w.Write(png[10:])
+		case "/slow.png":
+			time.Sleep(200 * time.Millisecond)
+			w.Write(png)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer srv.Close()
+
+	l := NewImageLoader()
+	l.CacheDir = t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		data, err := l.LoadImage(srv.URL + "/fpdf.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != len(png) {
+			t.Errorf("got %d bytes; want %d", len(data), len(png))
+		}
+	}
+	if hits != 1 {
+		t.Errorf("server was hit %d times; want 1 because of the cache", hits)
+	}
+
+	_, err = l.LoadImage(srv.URL + "/missing.png")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error; got %v", err)
+	}
+
+	l.MaxSize = 100
+	_, err = l.LoadImage(srv.URL + "/fpdf.png?big")
+	if err == nil {
+		t.Errorf("expected the size limit to be enforced")
+	}
+	_, err = l.LoadImage(srv.URL + "/chunked.png")
+	if err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("expected the size limit to be enforced without a Content-Length; got %v", err)
+	}
+	// the image cached earlier is too big now
+	_, err = l.LoadImage(srv.URL + "/fpdf.png")
+	if err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("expected the size limit to be enforced on the cache; got %v", err)
+	}
+
+	l.MaxSize = 0
+	l.Client.Timeout = 50 * time.Millisecond
+	_, err = l.LoadImage(srv.URL + "/slow.png")
+	if err == nil {
+		t.Errorf("expected a timeout")
+	}
+}
+
+func TestImageLoaderDataURL(t *testing.T) {
+	png, err := ioutil.ReadFile("image/fpdf.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := NewImageLoader().LoadImage("data:image/png;base64," + base64.StdEncoding.EncodeToString(png))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(png) {
+		t.Errorf("data URL was not decoded correctly")
+	}
+
+	data, err = NewImageLoader().LoadImage("data:,Hello%2C%20World")
+	if err != nil || string(data) != "Hello, World" {
+		t.Errorf("got %q, %v", data, err)
+	}
+}
+
+func TestRemoteImagesAreRendered(t *testing.T) {
+	png, err := ioutil.ReadFile("image/fpdf.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write(png)
+	}))
+	defer srv.Close()
+
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	md := fmt.Sprintf("![remote](%s/a.png)\n\n![inline](%s)\n", srv.URL, dataURL)
+
+	r := NewPdfRenderer("", "", "")
+	err = r.Process([]byte(md)).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.GetImageInfo(srv.URL+"/a.png") == nil {
+		t.Errorf("remote image was not rendered")
+	}
+}
@@ -0,0 +1,549 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"strings"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// Paragraphs and headings whose style is not left-aligned are laid out
+// here rather than written directly with gofpdf's Write. Their inline
+// content is collected as runs of text in a single style, broken into
+// lines, and then each line is positioned according to the alignment.
+// Soft line breaks in the markdown source are treated as spaces.
+// Paragraphs are also laid out here when the document language has
+// hyphenation patterns, so that words can be broken at line ends, and when
+// widow and orphan control is on, so that page breaks can be chosen before
+// any line is written. In right-to-left and auto documents every
+// paragraph and heading is laid out, so that its text can be reordered
+// line by line, see bidi.go.
+
+// Alignment values for Styler.Align.
+const (
+	AlignLeft    = "L"
+	AlignRight   = "R"
+	AlignCenter  = "C"
+	AlignJustify = "J"
+)
+
+// textRun is a piece of inline content: either some text in a single
+// style, an inline image, or a hard line break.
+type textRun struct {
+	text      string
+	style     Styler
+	url       string
+	img       pdfImage
+	w, h      float64 // size of an inline image
+	hardBreak bool
+	level     uint8 // bidirectional embedding level; odd is right-to-left
+}
+
+// textLayout collects the inline content of a block.
+type textLayout struct {
+	align           string
+	start           bool // no alignment was given, so it follows the direction
+	runs            []textRun
+	hyphenator      *Hyphenator
+	orphans, widows int
+	afterBullet     bool // the first line has a list bullet
+}
+
+// layoutWord is an unbreakable sequence of fragments, e.g. "**bold**text".
+type layoutWord struct {
+	frags     []layoutFragment
+	width     float64
+	space     float64 // width of the space before the word
+	spaceLvl  uint8   // embedding level of the space before the word
+	hardBreak bool    // a hard line break follows the word
+	hyphens   []hyphenPoint
+}
+
+// hyphenPoint is a place where a word may be broken across lines.
+type hyphenPoint struct {
+	frag   int     // the fragment containing the break
+	at     int     // byte offset of the break within the fragment's text
+	prefix float64 // width of the word up to the break
+	hyphen float64 // width of the hyphen added at the break
+}
+
+// layoutFragment is the part of a word that lies within a single run.
+type layoutFragment struct {
+	run   *textRun
+	text  string
+	width float64
+}
+
+// layoutLine is a line of words.
+type layoutLine struct {
+	words  []layoutWord
+	width  float64 // natural width, including single spaces
+	height float64
+	last   bool // the last line of the block, or one ending in a hard break
+	hyphen bool // the line ends with a hyphenated word
+}
+
+// startLayout begins collecting the inline content of a block if its style
+// requires it, or if it is a paragraph that is to be hyphenated or kept
+// free of widows and orphans. Other left-aligned blocks are written
+// directly as before.
+func (r *PdfRenderer) startLayout(s Styler, para bool) {
+	align := strings.ToUpper(s.Align)
+	start := align == ""
+	if start {
+		align = AlignLeft
+	}
+	layout := &textLayout{align: align, start: start}
+	if para {
+		layout.hyphenator = r.hyphenator
+		layout.orphans, layout.widows = r.Orphans, r.Widows
+	}
+	if c := r.cs.peek(); c.containerType == bf.Item && c.firstParagraph {
+		layout.afterBullet = true
+	}
+	if align == AlignLeft && r.direction() == DirectionLTR &&
+		layout.hyphenator == nil && layout.orphans < 2 && layout.widows < 2 {
+		return
+	}
+	if start && r.direction() != DirectionLTR {
+		r.tracer("... Layout", "align start")
+	} else {
+		r.tracer("... Layout", "align "+align)
+	}
+	r.layout = layout
+}
+
+// suspendLayout writes the content collected so far, so that a block can be
+// placed in the middle of a paragraph, and gives an empty layout like it to
+// carry on with afterwards, or nil if there is none.
+func (r *PdfRenderer) suspendLayout() *textLayout {
+	l := r.layout
+	if l == nil {
+		return nil
+	}
+	r.flushLayout()
+	return &textLayout{align: l.align, start: l.start, hyphenator: l.hyphenator, orphans: l.orphans, widows: l.widows}
+}
+
+// addRun adds inline content to the current layout.
+func (r *PdfRenderer) addRun(run textRun) {
+	r.layout.runs = append(r.layout.runs, run)
+}
+
+// addText adds text in the given style, linked to dest if it isn't blank.
+func (r *PdfRenderer) addText(s Styler, text, dest string) {
+	for _, span := range r.fontSpans(s, r.shape(s, text)) {
+		r.addRun(textRun{text: span.text, style: span.style, url: dest})
+	}
+}
+
+// flushLayout breaks the collected content into lines and writes them. It
+// leaves the cursor at the end of the last line, just as Write would.
+func (r *PdfRenderer) flushLayout() {
+	layout := r.layout
+	r.layout = nil
+	if layout == nil || len(layout.runs) == 0 {
+		return
+	}
+
+	lm, tm, rm, _ := r.Pdf.GetMargins()
+	pageW, pageH := r.Pdf.GetPageSize()
+	_, bm := r.Pdf.GetAutoPageBreak()
+	startX := r.Pdf.GetX()
+
+	runs, level := r.bidiRuns(layout.runs)
+	align := layout.align
+	if level == 1 {
+		r.tracer("... Layout", "right-to-left")
+		if layout.start {
+			align = AlignRight
+		}
+	}
+
+	words := r.measureWords(runs, layout.hyphenator)
+	lines := breakLines(words, pageW-rm-startX, pageW-rm-lm)
+	hyphens := 0
+	for _, line := range lines {
+		if line.hyphen {
+			hyphens++
+		}
+	}
+	r.tracer("... Layout", fmt.Sprintf("%d words in %d lines, %d hyphenated", len(words), len(lines), hyphens))
+
+	// choose the page breaks; the paragraph may only be moved as a whole
+	// if nothing, such as a list bullet, precedes it on its first line
+	heights := make([]float64, len(lines))
+	for i, line := range lines {
+		heights[i] = line.height
+	}
+	breaks := paginate(heights, r.Pdf.GetY(), tm, pageH-bm, layout.orphans, layout.widows, startX <= lm && !layout.afterBullet)
+	if len(breaks) > 0 {
+		r.tracer("... Layout", fmt.Sprintf("page breaks before lines %v", breaks))
+	}
+
+	for i, line := range lines {
+		x := lm
+		if i == 0 {
+			x = startX
+		}
+		newPage := len(breaks) > 0 && breaks[0] == i
+		if newPage {
+			breaks = breaks[1:]
+		}
+		r.writeLine(line, align, level == 1, x, pageW-rm-x, i == len(lines)-1, newPage)
+	}
+	r.setStyler(r.cs.peek().textStyle)
+}
+
+// measureWords splits the runs into words and measures them. A word may
+// span several runs if there is no space between them. If h is not nil,
+// the places where each word may be hyphenated are found too.
+func (r *PdfRenderer) measureWords(runs []textRun, h *Hyphenator) []layoutWord {
+	var words []layoutWord
+	spaceBefore := false
+	join := false // whether the next fragment continues the last word
+	var spaceLvl uint8
+
+	for i := range runs {
+		run := &runs[i]
+		if run.hardBreak {
+			if len(words) > 0 {
+				words[len(words)-1].hardBreak = true
+			}
+			join, spaceBefore = false, false
+			continue
+		}
+
+		if run.img != nil {
+			frag := layoutFragment{run: run, width: run.w}
+			words = appendFragment(words, frag, join, spaceBefore, r.spaceWidth(r.cs.peek().textStyle))
+			join, spaceBefore = true, false
+			continue
+		}
+
+		r.setStyler(run.style)
+		space := r.Pdf.GetStringWidth(" ")
+		for j, part := range strings.Split(run.text, " ") {
+			if j > 0 {
+				join, spaceBefore = false, true
+				spaceLvl = run.level
+			}
+			if part == "" {
+				continue
+			}
+			frag := layoutFragment{run: run, text: part, width: r.Pdf.GetStringWidth(part)}
+			n := len(words)
+			words = appendFragment(words, frag, join, spaceBefore, space)
+			if len(words) > n {
+				words[n].spaceLvl = spaceLvl
+			}
+			join, spaceBefore = true, false
+		}
+	}
+
+	if h != nil {
+		for i := range words {
+			words[i].hyphens = r.hyphenPoints(words[i], h)
+		}
+	}
+	return words
+}
+
+// hyphenPoints finds where a word of one or more text fragments may be
+// broken. Words containing inline images are not hyphenated.
+func (r *PdfRenderer) hyphenPoints(w layoutWord, h *Hyphenator) []hyphenPoint {
+	var sb strings.Builder
+	for _, f := range w.frags {
+		if f.run.img != nil {
+			return nil
+		}
+		sb.WriteString(f.text)
+	}
+
+	var points []hyphenPoint
+	frag, start, prefix := 0, 0, 0.0 // start and prefix are those of frag
+	for _, offset := range h.Hyphenate(sb.String()) {
+		for offset > start+len(w.frags[frag].text) {
+			start += len(w.frags[frag].text)
+			prefix += w.frags[frag].width
+			frag++
+		}
+		f := w.frags[frag]
+		r.setStyler(f.run.style)
+		at := offset - start
+		points = append(points, hyphenPoint{
+			frag:   frag,
+			at:     at,
+			prefix: prefix + r.Pdf.GetStringWidth(f.text[:at]),
+			hyphen: r.Pdf.GetStringWidth("-"),
+		})
+	}
+	return points
+}
+
+// hyphenate breaks a word at the last point that leaves the first part,
+// with its hyphen, no wider than room.
+func (w layoutWord) hyphenate(room float64) (head, tail layoutWord, ok bool) {
+	best := -1
+	for i, p := range w.hyphens {
+		if p.prefix+p.hyphen <= room {
+			best = i
+		}
+	}
+	if best < 0 {
+		return head, tail, false
+	}
+	p := w.hyphens[best]
+
+	// the parts of the fragment containing the break
+	f := w.frags[p.frag]
+	before := 0.0
+	for _, g := range w.frags[:p.frag] {
+		before += g.width
+	}
+	left := layoutFragment{run: f.run, text: f.text[:p.at] + "-", width: p.prefix - before + p.hyphen}
+	right := layoutFragment{run: f.run, text: f.text[p.at:], width: f.width - (p.prefix - before)}
+
+	head = layoutWord{space: w.space, spaceLvl: w.spaceLvl, width: p.prefix + p.hyphen}
+	head.frags = append(append(head.frags, w.frags[:p.frag]...), left)
+
+	tail = layoutWord{width: w.width - p.prefix, hardBreak: w.hardBreak}
+	if right.text != "" {
+		tail.frags = append(tail.frags, right)
+	}
+	tail.frags = append(tail.frags, w.frags[p.frag+1:]...)
+	shift := len(w.frags) - len(tail.frags) // fragments removed from the front
+	for _, q := range w.hyphens[best+1:] {
+		if q.frag == p.frag {
+			q.at -= p.at
+		}
+		q.frag -= shift
+		q.prefix -= p.prefix
+		tail.hyphens = append(tail.hyphens, q)
+	}
+	return head, tail, true
+}
+
+func appendFragment(words []layoutWord, frag layoutFragment, join, spaceBefore bool, space float64) []layoutWord {
+	if join && len(words) > 0 && !words[len(words)-1].hardBreak {
+		w := &words[len(words)-1]
+		w.frags = append(w.frags, frag)
+		w.width += frag.width
+		return words
+	}
+	word := layoutWord{frags: []layoutFragment{frag}, width: frag.width}
+	if len(words) > 0 && spaceBefore {
+		word.space = space
+	}
+	return append(words, word)
+}
+
+func (r *PdfRenderer) spaceWidth(s Styler) float64 {
+	r.setStyler(s)
+	return r.Pdf.GetStringWidth(" ")
+}
+
+// breakLines fills each line with as many words as fit, first-fit. The
+// first line may be shorter, e.g. after a list bullet. A word that doesn't
+// fit is hyphenated if it can be, and carried onto the next line.
+func breakLines(words []layoutWord, firstWidth, width float64) []layoutLine {
+	var lines []layoutLine
+	var line layoutLine
+	avail := firstWidth
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		space := 0.0
+		if len(line.words) > 0 {
+			space = w.space
+		}
+		for line.width+space+w.width > avail {
+			head, tail, ok := w.hyphenate(avail - line.width - space)
+			if ok {
+				line.words = append(line.words, head)
+				line.width += space + head.width
+				line.hyphen = true
+			} else if len(line.words) == 0 {
+				break // too wide for any line, so let it overflow
+			}
+			lines = append(lines, line)
+			line = layoutLine{}
+			avail = width
+			space = 0
+			if ok {
+				w = tail
+			}
+		}
+		line.width += space
+		line.words = append(line.words, w)
+		line.width += w.width
+		if w.hardBreak {
+			line.last = true
+			lines = append(lines, line)
+			line = layoutLine{}
+			avail = width
+		}
+	}
+
+	if len(line.words) > 0 {
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		lines[len(lines)-1].last = true
+	}
+
+	for i := range lines {
+		for _, w := range lines[i].words {
+			for _, f := range w.frags {
+				lines[i].height = maxFloat(lines[i].height, f.run.lineHeight())
+			}
+		}
+	}
+	return lines
+}
+
+func (run *textRun) lineHeight() float64 {
+	if run.img != nil {
+		return run.h
+	}
+	return run.style.Size + run.style.Spacing
+}
+
+// writeLine positions a line within the available width according to the
+// alignment, starting a new page first if asked to. Justified lines spread
+// the extra space between the words, except on the last line of the
+// paragraph, which is aligned to the left, or to the right if the
+// paragraph is right-to-left. The words and the spaces between them are
+// put into visual order by their embedding levels.
+func (r *PdfRenderer) writeLine(line layoutLine, align string, rtl bool, x, avail float64, final, newPage bool) {
+	if newPage {
+		r.addPage()
+	}
+	y := r.Pdf.GetY()
+
+	extra := 0.0
+	slack := avail - line.width
+	switch align {
+	case AlignRight:
+		x += slack
+	case AlignCenter:
+		x += slack / 2
+	case AlignJustify:
+		if !line.last && len(line.words) > 1 && slack > 0 {
+			extra = slack / float64(len(line.words)-1)
+		} else if rtl {
+			x += slack
+		}
+	}
+
+	// all text on the line shares a baseline, placed as CellFormat would
+	// place the largest font
+	fontSize := 0.0
+	for _, w := range line.words {
+		for _, f := range w.frags {
+			if f.run.img == nil {
+				fontSize = maxFloat(fontSize, f.run.style.Size)
+			}
+		}
+	}
+	baseline := y + 0.5*line.height + 0.3*fontSize
+
+	// the spaces between words are items too, with no fragment
+	type lineItem struct {
+		frag  *layoutFragment
+		width float64
+	}
+	var items []lineItem
+	var levels []uint8
+	for i := range line.words {
+		w := &line.words[i]
+		if i > 0 {
+			items = append(items, lineItem{width: w.space + extra})
+			levels = append(levels, w.spaceLvl)
+		}
+		for j := range w.frags {
+			items = append(items, lineItem{frag: &w.frags[j], width: w.frags[j].width})
+			levels = append(levels, w.frags[j].run.level)
+		}
+	}
+	order := reorderLevels(levels)
+	for k, i := range order {
+		if f := items[i].frag; f != nil {
+			r.writeFragment(*f, x, y, baseline, line.height)
+		} else if k > 0 && k < len(order)-1 {
+			r.writeGap(items[order[k-1]].frag, items[order[k+1]].frag, x, items[i].width, y, baseline, line.height)
+		}
+		x += items[i].width
+	}
+
+	if final {
+		r.Pdf.SetXY(x, y)
+	} else {
+		r.Pdf.SetY(y + line.height)
+	}
+}
+
+func (r *PdfRenderer) writeFragment(f layoutFragment, x, y, baseline, lh float64) {
+	run := f.run
+	top, h := y, lh
+	if run.img != nil {
+		top, h = y+(lh-run.h)/2, run.h
+		run.img.draw(r, x, top, run.w, run.h)
+	} else {
+		r.setStyler(run.style)
+		text := f.text
+		if run.level%2 == 1 {
+			text = reverseText(text)
+		}
+		r.Pdf.Text(x, baseline, text)
+	}
+
+	if run.url != "" {
+		r.Pdf.LinkString(x, top, f.width, h, run.url)
+	}
+}
+
+// writeGap continues the underline and the link of a link across the
+// space between two of its words, as Write does. The space is written
+// clipped to the gap, which justification may have widened, so that the
+// underline is the font's own.
+func (r *PdfRenderer) writeGap(before, after *layoutFragment, x, w, y, baseline, lh float64) {
+	if before == nil || after == nil || before.run.img != nil || after.run.img != nil {
+		return
+	}
+	b, a := before.run, after.run
+	if b.url == "" || b.url != a.url {
+		return
+	}
+	if underlined(b.style) && underlined(a.style) {
+		r.setStyler(b.style)
+		n := 1
+		if sw := r.Pdf.GetStringWidth(" "); sw > 0 {
+			n += int(w / sw)
+		}
+		r.Pdf.ClipRect(x, y, w, lh, false)
+		r.Pdf.Text(x, baseline, strings.Repeat(" ", n))
+		r.Pdf.ClipEnd()
+	}
+	r.Pdf.LinkString(x, y, w, lh, b.url)
+}
+
+func underlined(s Styler) bool {
+	return strings.ContainsAny(s.Style, "Uu")
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,141 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func word(width float64) layoutWord {
+	run := &textRun{style: Styler{Size: 10, Spacing: 4}}
+	return layoutWord{frags: []layoutFragment{{run: run, width: width}}, width: width, space: 5}
+}
+
+func TestBreakLines(t *testing.T) {
+	words := []layoutWord{word(30), word(30), word(30), word(30), word(100)}
+	words[0].space = 0
+	words[2].hardBreak = true
+
+	// first line is narrower, as after a bullet
+	lines := breakLines(words, 40, 70)
+
+	want := []int{1, 2, 1, 1}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines; want %d", len(lines), len(want))
+	}
+	for i, n := range want {
+		if len(lines[i].words) != n {
+			t.Errorf("line %d: got %d words; want %d", i+1, len(lines[i].words), n)
+		}
+		if lines[i].height != 14 {
+			t.Errorf("line %d: got height %v", i+1, lines[i].height)
+		}
+	}
+	if lines[0].last || !lines[1].last || lines[2].last || !lines[3].last {
+		t.Errorf("hard breaks and the final line should be marked last")
+	}
+	if lines[1].width != 65 {
+		t.Errorf("got width %v; want 65", lines[1].width)
+	}
+}
+
+func TestMeasureWordsJoinsRuns(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	bold := r.Normal
+	bold.Style = "b"
+	runs := []textRun{
+		{text: "some ", style: r.Normal},
+		{text: "bold", style: bold},
+		{text: "ly written  text", style: r.Normal},
+	}
+
+	words := r.measureWords(runs, nil)
+	if len(words) != 4 {
+		t.Fatalf("got %d words; want 4", len(words))
+	}
+	if len(words[1].frags) != 2 || words[1].frags[0].text != "bold" || words[1].frags[1].text != "ly" {
+		t.Errorf("expected 'boldly' to be one word; got %+v", words[1].frags)
+	}
+	if words[0].space != 0 || words[1].space == 0 {
+		t.Errorf("unexpected spacing %v %v", words[0].space, words[1].space)
+	}
+}
+
+func TestBreakLinesHyphenates(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Language = "en"
+	r.RenderHeader(nil, nil)
+	bold := r.Normal
+	bold.Style = "b"
+	runs := []textRun{
+		{text: "an ", style: r.Normal},
+		{text: "inter", style: bold},
+		{text: "nationalization", style: r.Normal},
+	}
+
+	words := r.measureWords(runs, r.hyphenator)
+	var point *hyphenPoint
+	for i, p := range words[1].hyphens {
+		if p.frag == 1 && p.at == len("nation") {
+			point = &words[1].hyphens[i]
+		}
+	}
+	if point == nil {
+		t.Fatalf("expected a hyphenation point after 'internation'; got %+v", words[1].hyphens)
+	}
+
+	// room for "an internation-" but not the whole word
+	width := words[0].width + words[1].space + point.prefix + point.hyphen + 1
+	lines := breakLines(words, width, width)
+
+	var got []string
+	for _, line := range lines {
+		var sb strings.Builder
+		for i, w := range line.words {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			for _, f := range w.frags {
+				sb.WriteString(f.text)
+			}
+		}
+		got = append(got, sb.String())
+	}
+	want := []string{"an internation-", "alization"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	if !lines[0].hyphen || lines[1].hyphen {
+		t.Errorf("only the first line should be marked as hyphenated")
+	}
+	if lines[0].width > width {
+		t.Errorf("line width %v exceeds %v", lines[0].width, width)
+	}
+}
+
+func TestLinkUnderlinedAcrossSpaces(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Normal.Align = AlignJustify
+	r.Pdf.SetCompression(false)
+	var buf bytes.Buffer
+	err := r.Process([]byte("See [the three words](https://example.com) here.\n")).Output(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+	if n := strings.Count(pdf, "re W n"); n != 2 {
+		t.Errorf("got %d underlined gaps; want 2", n)
+	}
+	if n := strings.Count(pdf, "/URI (https://example.com)"); n != 5 {
+		t.Errorf("got %d link areas; want 5, for three words and two gaps", n)
+	}
+}
+
+func TestDefaultsWriteDirectly(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.startLayout(r.Normal, true)
+	if r.layout != nil {
+		t.Errorf("a plain paragraph should be written directly")
+	}
+}
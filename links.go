@@ -0,0 +1,59 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// Headings are anchors, which the table of contents links to, named by
+// their IDs: either an explicit "{#id}" or the anchor name derived from the
+// heading text, as in GitHub and blackfriday's HTML renderer.
+
+// anchorLink gives the internal link ID for an anchor, creating it if this
+// is the first reference. The link's target is set when the anchor is
+// reached, which may be later in the document.
+func (r *PdfRenderer) anchorLink(name string) int {
+	if r.anchors == nil {
+		r.anchors = make(map[string]*anchor)
+	}
+	a, exists := r.anchors[name]
+	if !exists {
+		a = &anchor{link: r.Pdf.AddLink()}
+		r.anchors[name] = a
+	}
+	return a.link
+}
+
+// setAnchor makes the current position the target of an anchor.
+func (r *PdfRenderer) setAnchor(name string) {
+	if name == "" {
+		return
+	}
+	link := r.anchorLink(name)
+	a := r.anchors[name]
+	if !a.placed {
+		r.Pdf.SetLink(link, r.Pdf.GetY(), -1)
+		a.placed = true
+	}
+}
+
+// headingAnchor gives the anchor name for a heading.
+func headingAnchor(node *bf.Node) string {
+	if node.HeadingData.HeadingID != "" {
+		return node.HeadingData.HeadingID
+	}
+	return bf.SanitizedAnchorName(plainText(node))
+}
+
+type anchor struct {
+	link   int
+	placed bool
+}
@@ -24,8 +24,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"os"
+	"io/fs"
+	"math"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/phpdave11/gofpdf"
 	bf "github.com/russross/blackfriday/v2"
@@ -39,6 +41,7 @@ const (
 
 // Color is expressed in RGB components (0 - 255).
 // For a nice picker, see https://www.w3schools.com/colors/colors_picker.asp
+// In themes it is written in CSS hex form, e.g. "#0366d6".
 type Color struct {
 	Red, Green, Blue int
 }
@@ -46,14 +49,21 @@ type Color struct {
 // Styler is the struct to capture the styling features for text
 // Size and Spacing are specified in points.
 // The sum of Size and Spacing is used as line height value
-// in the gofpdf API
+// in the gofpdf API.
+// Align applies to paragraphs and headings in this style and is one of
+// AlignLeft (the default if blank), AlignRight, AlignCenter or AlignJustify.
 type Styler struct {
-	Font      string
-	Style     string
-	Size      float64
-	Spacing   float64
-	TextColor Color
-	FillColor Color
+	Font      string  `json:"font" yaml:"font"`
+	Style     string  `json:"style" yaml:"style"`
+	Size      float64 `json:"size" yaml:"size"`
+	Spacing   float64 `json:"spacing" yaml:"spacing"`
+	TextColor Color   `json:"textColor" yaml:"textColor"`
+	FillColor Color   `json:"fillColor" yaml:"fillColor"`
+	Align     string  `json:"align,omitempty" yaml:"align,omitempty"`
+
+	// FallbackFonts lists, separated by commas, the fonts to use in turn
+	// for characters that Font has no glyphs for.
+	FallbackFonts string `json:"fallbackFonts,omitempty" yaml:"fallbackFonts,omitempty"`
 }
 
 // PdfRenderer is the struct to manage conversion of a markdown object
@@ -65,8 +75,61 @@ type PdfRenderer struct {
 
 	// trace/log file - used if not blank
 	TracerFile  string
+	traceOut    io.Writer // set by WithTracer
 	traceWriter *bufio.Writer
 
+	// Extensions are the blackfriday extensions that the markdown is
+	// parsed with. Zero means bf.CommonExtensions.
+	Extensions bf.Extensions
+
+	// BaseDir is the directory against which relative image paths are
+	// resolved. If blank, the current working directory is used.
+	BaseDir string
+
+	// ImageFS, if not nil, supplies local images instead of the operating
+	// system's filesystem. BaseDir is then a slash-separated path within it.
+	ImageFS fs.FS
+
+	// ImageLoader obtains http, https and data URL images. It is a
+	// DefaultImageLoader unless replaced; nil disables such images.
+	ImageLoader ImageLoader
+
+	// EmojiShortcodes turns shortcodes such as ":rocket:" in the text
+	// into the emoji they name. It is on unless turned off.
+	EmojiShortcodes bool
+
+	// EmojiImages, if not nil, supplies images to draw emoji with, named
+	// by their code points as in the Twemoji set, e.g. "1f680.png"; see
+	// emoji.go. os.DirFS gives one for a directory.
+	EmojiImages fs.FS
+
+	// ImageAlign is the default horizontal alignment of images: "L", "C"
+	// or "R" (or "left", "center", "right"). Blank means left.
+	ImageAlign string
+
+	// ImageMaxWidth limits the width of images to this fraction of the
+	// content width. Zero means the whole content width.
+	ImageMaxWidth float64
+
+	// MissingImage chooses what happens when an image can't be loaded;
+	// see also ImageWarnings.
+	MissingImage  MissingImagePolicy
+	imageWarnings []ImageWarning
+	styleWarnings []StyleWarning // see StylesheetWarnings
+	images        map[string]pdfImage
+
+	// internal link targets, by anchor name
+	anchors map[string]*anchor
+
+	// page format and font directory given to New; pageSize is in points
+	// and is zero unless the paper has a custom size
+	orientation, paperSize, fontDir string
+	pageSize                        gofpdf.SizeType
+
+	// paper size set by front matter, in portrait; zero if it keeps that
+	// of Pdf
+	formatSize gofpdf.SizeType
+
 	// default margins for safe keeping
 	mleft, mtop, mright, mbottom float64
 
@@ -84,6 +147,32 @@ type PdfRenderer struct {
 	Blockquote  Styler
 	IndentValue float64
 
+	// QuoteIndent is the indentation of block quotes; zero means
+	// IndentValue.
+	QuoteIndent float64
+
+	// Bullets are the marks for items in unordered lists, one for each
+	// level of nesting; deeper lists reuse them in turn.
+	Bullets []string
+
+	// BorderColor is the colour of table borders.
+	BorderColor Color
+
+	// PlaceholderColor and PlaceholderFill are the border and the inside
+	// of the box drawn in place of a missing image.
+	PlaceholderColor, PlaceholderFill Color
+
+	// Background, if not nil, is the colour of every page.
+	Background *Color
+
+	// BackgroundImage is the path or URL of an image stretched over every
+	// page, resolved in the same way as images in the markdown.
+	BackgroundImage string
+	background      pdfImage
+
+	// fontCoverage holds the characters of each registered font family
+	fontCoverage map[string]glyphCoverage
+
 	// Headings
 	H1 Styler
 	H2 Styler
@@ -96,107 +185,205 @@ type PdfRenderer struct {
 	THeader Styler
 	TBody   Styler
 
+	// Figure captions
+	Caption Styler
+
+	// FigureCaptions controls whether images are captioned with their
+	// title or, if they have none, their alt text. It is off by default,
+	// since alt text is often written for readers who can't see the image.
+	FigureCaptions bool
+
+	// FigureLabel, if not blank, numbers the captions automatically
+	// using this label, e.g. "Figure" gives "Figure 1: ...".
+	FigureLabel string
+
+	// ListOfFigures adds a page listing the captioned figures at the
+	// end of the document, headed by ListOfFiguresTitle.
+	ListOfFigures      bool
+	ListOfFiguresTitle string
+	figures            []figure
+
+	// FrontMatter holds the YAML or TOML front matter found by Process at
+	// the start of the markdown, or nil if there was none.
+	FrontMatter map[string]interface{}
+
+	// Metadata describes the document. The title, subject, authors and
+	// keywords become the properties of the PDF file.
+	Metadata Metadata
+
+	// TitlePage adds a title page, laid out according to TitleLayout,
+	// before the body if the metadata include a title.
+	TitlePage   bool
+	TitleLayout TitlePageLayout
+
+	// TableOfContents adds a list of the headings, down to level
+	// TableOfContentsDepth, before the body. Each entry links to its
+	// heading.
+	TableOfContents      bool
+	TableOfContentsTitle string
+	TableOfContentsDepth int
+	tocAliases           map[*bf.Node]string
+
+	// Language is the language of the document, as a tag such as "en" or
+	// "de-DE". If there are hyphenation patterns for it, see HyphenatorFor,
+	// words in paragraphs are hyphenated where that avoids ragged or
+	// overflowing lines.
+	Language   string
+	hyphenator *Hyphenator
+
+	// Direction is the direction of the text: DirectionLTR,
+	// DirectionRTL, or DirectionAuto to take each paragraph's direction
+	// from its first letter. If blank, it is right-to-left when Language
+	// is written that way, e.g. "ar" or "he", and otherwise left-to-right.
+	// Right-to-left paragraphs are aligned to the right unless their
+	// style says otherwise, and lists and block quotes are indented from
+	// the right. Text in either direction is reordered for writing, see
+	// bidi.go.
+	Direction string
+
+	// KeepWithNext is the number of lines of the following block that must
+	// fit on the same page as a heading; if they don't, the heading starts
+	// a new page. Zero, the default, allows headings to be left at the foot
+	// of a page; two is the usual setting.
+	KeepWithNext int
+
+	// Orphans and Widows are the fewest lines of a paragraph that may be
+	// left at the foot of a page or carried over to the top of the next.
+	// Values below 2, such as the default of zero, turn the control off;
+	// paragraphs are laid out line by line when it is on.
+	Orphans, Widows int
+
+	// PageBreakLevel makes headings of this level and above start a new
+	// page, e.g. 1 for every H1. Zero means none do.
+	PageBreakLevel int
+
+	// PageBreakComment is the text of an HTML comment that forces a new
+	// page, as in "<!-- pagebreak -->". Blank disables such comments.
+	PageBreakComment string
+
 	cs       states
-	markdown []byte // the source content
+	layout   *textLayout // inline content of an aligned block
+	markdown []byte      // the source content
+	err      error       // set if rendering has to stop
 }
 
 // NewPdfRenderer creates and configures an PdfRenderer object,
-// which satisfies the BlackFriday Renderer interface.
+// which satisfies the BlackFriday Renderer interface. It is the same as
+// New with WithOrientation, WithPaperSize and WithFontDir.
 //
 // Any the parameters may be blank, with the defaults being
 // "portrait", "A4", "."
 //
 // It is not safe to use instances in more than one goroutine.
 func NewPdfRenderer(orientation, paperSize, fontDir string) *PdfRenderer {
-
-	r := new(PdfRenderer)
-
-	// Normal Text
-	r.Normal = Styler{Font: sansFont, Style: "", Size: 10, Spacing: 4, TextColor: Black, FillColor: White}
-
-	// Link text
-	r.Link = Styler{Font: sansFont, Style: "u", Size: 10, Spacing: 4, TextColor: ColorOf("#0366d6"), FillColor: White}
-
-	// Backticked text ('code block')
-	r.Backtick = Styler{Font: "Courier", Style: "", Size: 10, Spacing: 4, TextColor: Color{37, 27, 14}, FillColor: Grey(230)}
-
-	// Headings
-	r.H1 = Styler{Font: sansFont, Style: "b", Size: 18, Spacing: 6, TextColor: Black, FillColor: White}
-	r.H2 = Styler{Font: sansFont, Style: "b", Size: 16, Spacing: 6, TextColor: Black, FillColor: White}
-	r.H3 = Styler{Font: sansFont, Style: "b", Size: 14, Spacing: 6, TextColor: Black, FillColor: White}
-	r.H4 = Styler{Font: sansFont, Style: "b", Size: 12, Spacing: 6, TextColor: Black, FillColor: White}
-	r.H5 = Styler{Font: sansFont, Style: "b", Size: 10, Spacing: 6, TextColor: Black, FillColor: White}
-	r.H6 = Styler{Font: sansFont, Style: "b", Size: 9, Spacing: 6, TextColor: Black, FillColor: White}
-
-	//r.inBlockquote = false
-	//r.inHeading = false
-	r.Blockquote = Styler{Font: sansFont, Style: "i", Size: 10, Spacing: 4, TextColor: Black, FillColor: White}
-
-	r.THeader = Styler{Font: sansFont, Style: "B", Size: 10, Spacing: 4, TextColor: Black, FillColor: Grey(180)}
-	r.TBody = Styler{Font: sansFont, Style: "", Size: 10, Spacing: 4, TextColor: Black, FillColor: Grey(240)}
-
-	r.Pdf = gofpdf.New(orientation, "pt", paperSize, fontDir)
-	r.Pdf.AddPage()
-	// set default font
-	r.setStyler(r.Normal)
-	r.mleft, r.mtop, r.mright, r.mbottom = r.Pdf.GetMargins()
-	r.em = r.Pdf.GetStringWidth("m")
-	r.IndentValue = 3 * r.em
-
-	//r.current = r.normal // set default
-	r.cs = states{stack: make([]*containerState, 0)}
-	initcurrent := &containerState{containerType: bf.Paragraph,
-		listkind:  notlist,
-		textStyle: r.Normal, leftMargin: r.mleft}
-	r.cs.push(initcurrent)
+	// these options can't fail
+	r, _ := New(WithOrientation(orientation), WithPaperSize(paperSize), WithFontDir(fontDir))
 	return r
 }
 
 // Process sets the markdown source and must be called prior to
 // ToFile or Output.
+//
+// Any front matter at the start of the markdown is removed and parsed; it
+// is then available as FrontMatter. The keys title, subtitle, author (or
+// authors), date, version, subject, keywords, logo, titlepage, lang, dir, toc,
+// paper and orientation configure the document. Paper and orientation apply
+// to every page, as the first page is only added when rendering starts,
+// unless something has already been drawn on Pdf. An error in the front
+// matter is returned by ToFile or Output.
 func (r *PdfRenderer) Process(markdown []byte) *PdfRenderer {
-	r.markdown = convertCRNL(markdown)
+	r.err = nil
+	markdown = convertCRNL(markdown)
+	format, front, rest := splitFrontMatter(markdown)
+	r.markdown = rest
+	r.FrontMatter = nil
+	if format != "" {
+		fm, err := parseFrontMatter(format, front)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		r.FrontMatter = fm
+		r.applyFrontMatter(fm)
+	}
 	return r
 }
 
+// setPageFormat sets the orientation and paper size of the pages added
+// from now on. A blank paper size keeps the current one, which may be a
+// custom size. The first page is only added when rendering starts, so
+// front matter sets the format of the whole document.
+func (r *PdfRenderer) setPageFormat(orientation, paperSize string) {
+	r.orientation = orientation
+	if paperSize != "" {
+		r.formatSize = r.Pdf.GetPageSizeStr(paperSize)
+	} else if r.formatSize.Wd == 0 {
+		w, h := r.Pdf.GetPageSize()
+		r.formatSize = gofpdf.SizeType{Wd: math.Min(w, h), Ht: math.Max(w, h)}
+	}
+}
+
+// addPage starts a new page in the format set by the front matter, if
+// any; otherwise in that of Pdf.
+func (r *PdfRenderer) addPage() {
+	if r.formatSize.Wd == 0 {
+		r.Pdf.AddPage()
+		return
+	}
+	r.Pdf.AddPageFormat(r.orientation, r.formatSize)
+}
+
 // ToFile renders to a PDF file.
 func (r *PdfRenderer) ToFile(pdfFile string) error {
 	// try to open tracer
-	if r.TracerFile != "" {
-		f, err := os.Create(r.TracerFile)
-		if err != nil {
-			return fmt.Errorf("os.Create() on tracefile: %w", err)
-		}
-		defer f.Close()
-		r.traceWriter = bufio.NewWriter(f)
-		defer r.traceWriter.Flush()
+	stop, err := r.startTracer()
+	if err != nil {
+		return err
 	}
+	defer stop()
 
-	_ = bf.Run(r.markdown, bf.WithRenderer(r))
+	r.run()
+	if r.err != nil {
+		return r.err
+	}
 
-	err := r.Pdf.OutputFileAndClose(pdfFile)
+	err = r.Pdf.OutputFileAndClose(pdfFile)
 	if err != nil {
 		return fmt.Errorf("fpdf.ToFile() error on %v: %w", pdfFile, err)
 	}
 	return nil
 }
 
+// run renders the markdown with the chosen extensions.
+func (r *PdfRenderer) run() {
+	opts := []bf.Option{bf.WithRenderer(r)}
+	if r.Extensions != 0 {
+		opts = append(opts, bf.WithExtensions(r.Extensions))
+	}
+	if !r.Pdf.Err() {
+		_ = bf.Run(r.markdown, opts...)
+	}
+	if r.err == nil && r.Pdf.Err() {
+		// e.g. a font that isn't registered
+		r.err = fmt.Errorf("fpdf: %w", r.Pdf.Error())
+	}
+}
+
 // Output renders PDF content to a writer.
 func (r *PdfRenderer) Output(w io.Writer) error {
 	// try to open tracer
-	if r.TracerFile != "" {
-		f, err := os.Create(r.TracerFile)
-		if err != nil {
-			return fmt.Errorf("os.Create() on tracefile: %w", err)
-		}
-		defer f.Close()
-		r.traceWriter = bufio.NewWriter(f)
-		defer r.traceWriter.Flush()
+	stop, err := r.startTracer()
+	if err != nil {
+		return err
 	}
+	defer stop()
 
-	_ = bf.Run(r.markdown, bf.WithRenderer(r))
+	r.run()
+	if r.err != nil {
+		return r.err
+	}
 
-	err := r.Pdf.Output(w)
+	err = r.Pdf.Output(w)
 	if err != nil {
 		return fmt.Errorf("fpdf.Output(): %w", err)
 	}
@@ -230,15 +417,149 @@ func (r *PdfRenderer) setStyler(s Styler) {
 }
 
 func (r *PdfRenderer) write(s Styler, t string) {
-	r.Pdf.Write(s.Size+s.Spacing, t)
+	spans := r.fontSpans(s, r.visual(r.shape(s, t)))
+	for _, span := range spans {
+		if span.style.Font != s.Font {
+			r.setStyler(span.style)
+		}
+		r.Pdf.Write(s.Size+s.Spacing, span.text)
+		if span.style.Font != s.Font {
+			r.setStyler(s)
+		}
+	}
 }
 
 func (r *PdfRenderer) multiCell(s Styler, t string) {
-	r.Pdf.MultiCell(0, s.Size+s.Spacing, t, "", "", true)
+	t = visualLines(r.shape(s, t), leftToRight)
+	h := s.Size + s.Spacing
+	spans := r.fontSpans(s, t)
+	if len(spans) == 1 {
+		if spans[0].style.Font != s.Font {
+			r.setStyler(spans[0].style)
+			defer r.setStyler(s)
+		}
+		r.Pdf.MultiCell(0, h, t, "", "", true)
+		return
+	}
+
+	// MultiCell writes in one font, so instead each line is filled and its
+	// spans are written over it, wrapping where MultiCell would
+	left, _, right, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	cm := r.Pdf.GetCellMargin()
+	r.Pdf.SetCellMargin(0)
+	defer r.Pdf.SetCellMargin(cm)
+	defer r.setStyler(s)
+
+	startLine := func() {
+		r.Pdf.SetX(left)
+		r.Pdf.CellFormat(0, h, "", "", 0, "", true, 0, "")
+		r.Pdf.SetX(left + cm)
+	}
+	startLine()
+	spans[len(spans)-1].text = strings.TrimSuffix(spans[len(spans)-1].text, "\n")
+	for _, span := range spans {
+		r.setStyler(span.style)
+		for i, piece := range strings.Split(span.text, "\n") {
+			if i > 0 {
+				r.Pdf.Ln(h)
+				startLine()
+			}
+			for piece != "" {
+				n := r.fitText(piece, pageW-right-cm-r.Pdf.GetX())
+				if n == 0 && r.Pdf.GetX() > left+cm {
+					r.Pdf.Ln(h)
+					startLine()
+					continue
+				}
+				if n == 0 {
+					_, n = utf8.DecodeRuneInString(piece)
+				}
+				r.Pdf.CellFormat(r.Pdf.GetStringWidth(piece[:n]), h, piece[:n], "", 0, "L", false, 0, "")
+				if piece = piece[n:]; piece != "" {
+					r.Pdf.Ln(h)
+					startLine()
+				}
+			}
+		}
+	}
+	r.Pdf.Ln(h)
+}
+
+// fitText gives the length of the longest part of text that fits in width
+// w in the current font, breaking after a space if there is one.
+func (r *PdfRenderer) fitText(text string, w float64) int {
+	n, brk := 0, 0
+	for i, ch := range text {
+		end := i + utf8.RuneLen(ch)
+		if r.Pdf.GetStringWidth(text[:end]) > w {
+			if brk > 0 {
+				return brk
+			}
+			return n
+		}
+		n = end
+		if ch == ' ' {
+			brk = end
+		}
+	}
+	return n
+}
+
+// cellFormat writes text in a table cell of width w, splitting it into
+// spans where it needs to change font.
+func (r *PdfRenderer) cellFormat(s Styler, w, h float64, text, border, align string, fill bool) {
+	spans := r.fontSpans(s, text)
+	if len(spans) == 1 {
+		r.setStyler(spans[0].style)
+		r.Pdf.CellFormat(w, h, text, border, 0, align, fill, 0, "")
+		return
+	}
+
+	// CellFormat writes in one font, so the cell is drawn empty and its
+	// spans are written inside it
+	x, y := r.Pdf.GetXY()
+	r.setStyler(s)
+	r.Pdf.CellFormat(w, h, "", border, 0, "", fill, 0, "")
+	cm := r.Pdf.GetCellMargin()
+	dx := cm
+	switch align {
+	case AlignCenter:
+		dx = (w - r.spansWidth(spans)) / 2
+	case AlignRight:
+		dx = w - cm - r.spansWidth(spans)
+	}
+	r.Pdf.SetCellMargin(0)
+	r.Pdf.SetXY(x+dx, y)
+	for _, span := range spans {
+		r.setStyler(span.style)
+		r.Pdf.CellFormat(r.Pdf.GetStringWidth(span.text), h, span.text, "", 0, "L", false, 0, "")
+	}
+	r.Pdf.SetCellMargin(cm)
+	r.Pdf.SetXY(x+w, y)
+	r.setStyler(s)
+}
+
+// spansWidth gives the width of text split into font spans.
+func (r *PdfRenderer) spansWidth(spans []fontSpan) float64 {
+	w := 0.0
+	for _, span := range spans {
+		r.setStyler(span.style)
+		w += r.Pdf.GetStringWidth(span.text)
+	}
+	return w
 }
 
 func (r *PdfRenderer) writeLink(s Styler, display, url string) {
-	r.Pdf.WriteLinkString(s.Size+s.Spacing, display, url)
+	for _, span := range r.fontSpans(s, r.visual(r.shape(s, display))) {
+		if span.style.Font != s.Font {
+			r.setStyler(span.style)
+		}
+		r.Pdf.WriteLinkString(s.Size+s.Spacing, span.text, url)
+		if span.style.Font != s.Font {
+			r.setStyler(s)
+		}
+	}
 }
 
 // RenderNode is a default renderer of a single node of a syntax tree. For
@@ -253,15 +574,28 @@ func (r *PdfRenderer) writeLink(s Styler, display, url string) {
 // traversal to the next node.
 // (above taken verbatim from the blackfriday v2 package)
 func (r *PdfRenderer) RenderNode(w io.Writer, node *bf.Node, entering bool) bf.WalkStatus {
+	if r.Pdf.Err() {
+		// gofpdf can't go on, and panics if it has no font
+		return bf.Terminate
+	}
 	switch node.Type {
 	case bf.Text:
 		r.processText(node)
 	case bf.Softbreak:
-		r.tracer("Softbreak", "Output newline")
-		r.cr()
+		if r.layout != nil {
+			r.tracer("Softbreak", "Output space")
+			r.addText(r.cs.peek().textStyle, " ", "")
+		} else {
+			r.tracer("Softbreak", "Output newline")
+			r.cr()
+		}
 	case bf.Hardbreak:
 		r.tracer("Hardbreak", "Output newline")
-		r.cr()
+		if r.layout != nil {
+			r.addRun(textRun{hardBreak: true})
+		} else {
+			r.cr()
+		}
 	case bf.Emph:
 		r.processEmph(node, entering)
 	case bf.Strong:
@@ -277,12 +611,17 @@ func (r *PdfRenderer) RenderNode(w io.Writer, node *bf.Node, entering bool) bf.W
 	case bf.Link:
 		r.processLink(node, entering)
 	case bf.Image:
-		r.processImage(node, entering)
+		return r.processImage(node, entering)
 	case bf.Code:
 		r.processCode(node)
 	case bf.Document:
 		r.tracer("Document", "Not Handled")
 	case bf.Paragraph:
+		if entering && isPageBreakParagraph(node) {
+			r.tracer("Paragraph", string(node.FirstChild.Literal))
+			r.pageBreak()
+			return bf.SkipChildren
+		}
 		r.processParagraph(node, entering)
 	case bf.BlockQuote:
 		r.processBlockQuote(node, entering)
@@ -314,14 +653,37 @@ func (r *PdfRenderer) RenderNode(w io.Writer, node *bf.Node, entering bool) bf.W
 	return bf.GoToNext
 }
 
-// RenderHeader is not supported.
+// RenderHeader prepares for rendering the document.
 func (r *PdfRenderer) RenderHeader(w io.Writer, ast *bf.Node) {
-	r.tracer("RenderHeader", "Not handled")
+	r.tracer("RenderHeader", "")
+	if r.Pdf.PageNo() == 0 {
+		r.addPage()
+	}
+	// pick up any changes made to Normal since construction
+	r.cs.stack[0].textStyle = r.Normal
+
+	r.hyphenator = HyphenatorFor(r.Language)
+	if r.hyphenator != nil {
+		r.tracer("... Hyphenation", r.Language)
+	}
+
+	r.setProperties()
+	r.startBackground()
+	if !r.Pdf.Err() {
+		r.renderTitlePage()
+	}
+	if !r.Pdf.Err() {
+		r.renderTableOfContents(ast)
+	}
 }
 
-// RenderFooter is not supported.
+// RenderFooter adds the list of figures, if enabled.
 func (r *PdfRenderer) RenderFooter(w io.Writer, ast *bf.Node) {
-	r.tracer("RenderFooter", "Not handled")
+	r.tracer("RenderFooter", "")
+	if r.Pdf.Err() {
+		return
+	}
+	r.renderListOfFigures()
 }
 
 func (r *PdfRenderer) cr() {
@@ -333,7 +695,7 @@ func (r *PdfRenderer) cr() {
 
 // Tracer traces parse and pdf generation activity.
 func (r *PdfRenderer) tracer(source, msg string) {
-	if r.TracerFile != "" {
+	if r.traceWriter != nil {
 		indent := strings.Repeat("-", len(r.cs.stack)-1)
 		r.traceWriter.WriteString(fmt.Sprintf("%v[%v] %v\n", indent, source, msg))
 	}
@@ -25,11 +25,12 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 // compare the results visually against (e.g.) https://md2pdf.netlify.app/
 
-func testit(name string, t *testing.T) {
+func testit(name string, t *testing.T, setup ...func(r *PdfRenderer)) {
 	inputDir := "./testdata/"
 	input := path.Join(inputDir, name)
 
@@ -49,6 +50,9 @@ func testit(name string, t *testing.T) {
 
 	r := NewPdfRenderer("", "", "")
 	r.TracerFile = path.Join(inputDir, base) + ".log"
+	for _, fn := range setup {
+		fn(r)
+	}
 
 	err = r.Process(markdown).ToFile(pdfFile)
 	if err != nil {
@@ -151,3 +155,98 @@ func TestLinksShortcut(t *testing.T) {
 func TestTidyness(t *testing.T) {
 	testit("Tidyness.md", t)
 }
+
+func TestImageBaseDir(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.BaseDir = "image"
+
+	err := r.Process([]byte("![gopher](hiking.png)\n")).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.GetImageInfo(path.Join("image", "hiking.png")) == nil {
+		t.Errorf("image was not loaded relative to %s", r.BaseDir)
+	}
+}
+
+func TestImageFS(t *testing.T) {
+	png, err := ioutil.ReadFile("image/fpdf.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewPdfRenderer("", "", "")
+	r.ImageFS = fstest.MapFS{"docs/img/logo.png": &fstest.MapFile{Data: png}}
+	r.BaseDir = "docs"
+
+	err = r.Process([]byte("![logo](./img/logo.png)\n\n![missing](img/none.png)\n")).Output(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.GetImageInfo("docs/img/logo.png") == nil {
+		t.Errorf("image was not loaded from ImageFS")
+	}
+}
+
+func TestImageScaling(t *testing.T) {
+	testit("Image scaling.md", t)
+}
+
+func TestSVGImages(t *testing.T) {
+	testit("SVG images.md", t)
+}
+
+func TestAlignment(t *testing.T) {
+	testit("Alignment.md", t, func(r *PdfRenderer) {
+		r.Normal.Align = AlignJustify
+		r.Blockquote.Align = AlignRight
+		r.H1.Align = AlignCenter
+		r.H2.Align = AlignCenter
+	})
+}
+
+func TestHyphenation(t *testing.T) {
+	testit("Hyphenation.md", t, func(r *PdfRenderer) {
+		r.Language = "en-US"
+		r.Normal.Align = AlignJustify
+		r.Pdf.SetRightMargin(300)
+	})
+}
+
+func TestPageBreaks(t *testing.T) {
+	testit("Page breaks.md", t, func(r *PdfRenderer) {
+		r.PageBreakLevel = 1
+	})
+}
+
+func TestTitlePage(t *testing.T) {
+	testit("Title page.md", t, func(r *PdfRenderer) {
+		r.Metadata = Metadata{
+			Title:    "Markdown to PDF",
+			Subtitle: "A title page from metadata",
+			Authors:  []string{"Cecil New", "Rick Beton"},
+			Date:     "2021-03-14",
+			Version:  "Version 1.2",
+			Logo:     "image/fpdf.png",
+		}
+		r.TitlePage = true
+	})
+}
+
+func TestFrontMatter(t *testing.T) {
+	testit("Front matter.md", t)
+}
+
+func TestThemes(t *testing.T) {
+	testit("Themes.md", t)
+}
+
+func TestStylesheet(t *testing.T) {
+	testit("Stylesheet.md", t, func(r *PdfRenderer) {
+		r.BaseDir = "testdata"
+	})
+}
+
+func TestDarkTheme(t *testing.T) {
+	testit("Dark theme.md", t)
+}
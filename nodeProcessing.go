@@ -21,46 +21,77 @@ package mdtopdf
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
-	"github.com/phpdave11/gofpdf"
 	bf "github.com/russross/blackfriday/v2"
 )
 
 func (r *PdfRenderer) processText(node *bf.Node) {
 	currentStyle := r.cs.peek().textStyle
 	r.setStyler(currentStyle)
-	s := string(node.Literal)
+	s := literal(node)
+	if followsBlockImage(node) {
+		s = strings.TrimLeft(s, " \t\n")
+	}
 	s = strings.Replace(s, "\n", " ", -1)
+	if r.EmojiShortcodes {
+		s = expandShortcodes(s, func(emoji string) bool {
+			return r.canDrawEmoji(currentStyle, emoji)
+		})
+	}
 	r.tracer("Text", s)
 
-	if r.cs.peek().containerType == bf.Link {
+	// emoji with images are drawn as inline images, except in table
+	// cells, which can only hold text
+	if spans := r.emojiSpans(s); spans != nil && r.cs.peek().containerType != bf.TableCell {
+		for _, span := range spans {
+			if span.img != nil {
+				r.placeEmoji(span.img)
+			} else {
+				r.outputText(currentStyle, span.text)
+			}
+		}
+		return
+	}
+	r.outputText(currentStyle, s)
+}
+
+// outputText writes text according to the container it is in.
+func (r *PdfRenderer) outputText(currentStyle Styler, s string) {
+	if r.layout != nil {
+		dest := ""
+		if r.cs.peek().containerType == bf.Link {
+			dest = r.cs.peek().destination
+		}
+		r.addText(currentStyle, s, dest)
+	} else if r.cs.peek().containerType == bf.Link {
 		r.writeLink(currentStyle, s, r.cs.peek().destination)
 	} else if r.cs.peek().containerType == bf.Heading {
 		//r.cr() // add space before heading
 		r.write(currentStyle, s)
 	} else if r.cs.peek().containerType == bf.TableCell {
+		s = r.shape(currentStyle, s)
 		if r.cs.peek().isHeader {
-			r.setStyler(currentStyle)
 			// get the string width of header value
-			hw := r.Pdf.GetStringWidth(s) + (2 * r.em)
+			hw := r.spansWidth(r.fontSpans(currentStyle, s)) + (2 * r.em)
 			// now append it
 			cellwidths = append(cellwidths, hw)
 			// now write it...
-			h, _ := r.Pdf.GetFontSize()
-			h += currentStyle.Spacing
+			h := currentStyle.Size + currentStyle.Spacing
 			r.tracer("... table header cell",
 				fmt.Sprintf("Width=%v, height=%v", hw, h))
 
-			r.Pdf.CellFormat(hw, h, s, "1", 0, "C", true, 0, "")
+			r.cellFormat(currentStyle, hw, h, r.visual(s), "1", AlignCenter, true)
 		} else {
-			r.setStyler(currentStyle)
 			hw := cellwidths[curdatacell]
 			h := currentStyle.Size + currentStyle.Spacing
 			r.tracer("... table body cell",
 				fmt.Sprintf("Width=%v, height=%v", hw, h))
-			r.Pdf.CellFormat(hw, h, s, "LR", 0, "", fill, 0, "")
+			align := ""
+			if r.paragraphLevel(s) == 1 {
+				align = AlignRight
+			}
+			r.cellFormat(currentStyle, hw, h, r.visual(s), "LR", align, fill)
 		}
 	} else {
 		r.write(currentStyle, s)
@@ -93,13 +124,20 @@ func (r *PdfRenderer) processList(node *bf.Node, entering bool) {
 	if entering {
 		r.tracer(fmt.Sprintf("%v List (entering)", kind),
 			fmt.Sprintf("%v", node.ListData))
-		r.Pdf.SetLeftMargin(r.cs.peek().leftMargin + r.IndentValue)
-		r.tracer("... List Left Margin",
-			fmt.Sprintf("set to %v", r.cs.peek().leftMargin+r.IndentValue))
 		x := &containerState{containerType: bf.List,
 			textStyle: r.Normal, itemNumber: 0,
 			listkind:   kind,
-			leftMargin: r.cs.peek().leftMargin + r.IndentValue}
+			leftMargin: r.cs.peek().leftMargin + r.IndentValue,
+			rtl:        r.rtlBlock(plainText(node))}
+		if x.rtl {
+			// mirrored, so the list is indented from the right
+			x.leftMargin = r.cs.peek().leftMargin
+			r.indentRight(r.IndentValue)
+		} else {
+			r.Pdf.SetLeftMargin(x.leftMargin)
+			r.tracer("... List Left Margin",
+				fmt.Sprintf("set to %v", x.leftMargin))
+		}
 		// before pushing check to see if this is a sublist
 		// if so, then output a newline
 		/*
@@ -111,9 +149,13 @@ func (r *PdfRenderer) processList(node *bf.Node, entering bool) {
 	} else {
 		r.tracer(fmt.Sprintf("%v List (leaving)", kind),
 			fmt.Sprintf("%v", node.ListData))
-		r.Pdf.SetLeftMargin(r.cs.peek().leftMargin - r.IndentValue)
-		r.tracer("... Reset List Left Margin",
-			fmt.Sprintf("re-set to %v", r.cs.peek().leftMargin-r.IndentValue))
+		if r.cs.peek().rtl {
+			r.indentRight(-r.IndentValue)
+		} else {
+			r.Pdf.SetLeftMargin(r.cs.peek().leftMargin - r.IndentValue)
+			r.tracer("... Reset List Left Margin",
+				fmt.Sprintf("re-set to %v", r.cs.peek().leftMargin-r.IndentValue))
+		}
 		r.cs.pop()
 		if len(r.cs.stack) < 2 {
 			r.cr()
@@ -131,13 +173,18 @@ func (r *PdfRenderer) processItem(node *bf.Node, entering bool) {
 			textStyle: r.Normal, itemNumber: r.cs.peek().itemNumber + 1,
 			listkind:       r.cs.peek().listkind,
 			firstParagraph: true,
-			leftMargin:     r.cs.peek().leftMargin}
+			leftMargin:     r.cs.peek().leftMargin,
+			rtl:            r.cs.peek().rtl}
 		// add bullet or itemnumber; then set left margin for the
 		// text/paragraphs in the item
 		r.cs.push(x)
+		if x.rtl {
+			r.rtlBullet()
+			return
+		}
 		if r.cs.peek().listkind == unordered {
 			r.Pdf.CellFormat(3*r.em, r.Normal.Size+r.Normal.Spacing,
-				"-",
+				r.bullet(),
 				"", 0, "RB", false, 0, "")
 		} else if r.cs.peek().listkind == ordered {
 			r.Pdf.CellFormat(3*r.em, r.Normal.Size+r.Normal.Spacing,
@@ -154,12 +201,62 @@ func (r *PdfRenderer) processItem(node *bf.Node, entering bool) {
 			fmt.Sprintf("%v", node.ListData))
 		// before we output the new line, reset left margin
 		r.Pdf.SetLeftMargin(r.cs.peek().leftMargin)
+		if r.cs.peek().rtl {
+			r.indentRight(-4 * r.em)
+		}
 		//r.cr()
 		r.cs.parent().itemNumber++
 		r.cs.pop()
 	}
 }
 
+// rtlBullet adds the bullet or item number of an item in a right-to-left
+// list at the right, and sets the right margin for the text of the item,
+// mirroring what processItem does for other lists.
+func (r *PdfRenderer) rtlBullet() {
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	mark := ""
+	switch r.cs.peek().listkind {
+	case unordered:
+		mark = r.bullet()
+	case ordered:
+		mark = visualOrder(fmt.Sprintf("%v.", r.cs.peek().itemNumber), 1)
+	}
+	if mark != "" {
+		r.Pdf.SetX(pageW - rm - 3*r.em)
+		r.Pdf.CellFormat(3*r.em, r.Normal.Size+r.Normal.Spacing,
+			mark, "", 0, "LB", false, 0, "")
+	}
+	r.indentRight(4 * r.em)
+	r.Pdf.SetX(lm)
+}
+
+// indentRight moves the right margin in, or out if by is negative.
+func (r *PdfRenderer) indentRight(by float64) {
+	_, _, rm, _ := r.Pdf.GetMargins()
+	r.Pdf.SetRightMargin(rm + by)
+	r.tracer("... Right Margin", fmt.Sprintf("set to %v", rm+by))
+}
+
+// bullet gives the mark for an item of an unordered list, according to how
+// deeply the list is nested.
+func (r *PdfRenderer) bullet() string {
+	if len(r.Bullets) == 0 {
+		return "-"
+	}
+	depth := 0
+	for _, c := range r.cs.stack {
+		if c.containerType == bf.List {
+			depth++
+		}
+	}
+	if depth > 0 {
+		depth--
+	}
+	return r.Bullets[depth%len(r.Bullets)]
+}
+
 func (r *PdfRenderer) processEmph(node *bf.Node, entering bool) {
 	if entering {
 		r.tracer("Emph (entering)", "")
@@ -199,32 +296,62 @@ func (r *PdfRenderer) processLink(node *bf.Node, entering bool) {
 	}
 }
 
-func (r *PdfRenderer) processImage(node *bf.Node, entering bool) {
-	// while this has entering and leaving states, it doesn't appear
-	// to be useful except for other markup languages to close the tag
-	if entering {
-		r.tracer("Image (entering)",
-			fmt.Sprintf("Destination[%v] Title[%v]",
-				string(node.LinkData.Destination),
-				string(node.LinkData.Title)))
-		// following changes suggested by @sirnewton01, issue #6
-		// does file exist?
-		var imgPath = string(node.LinkData.Destination)
-		_, err := os.Stat(imgPath)
-		if err == nil {
-			r.Pdf.ImageOptions(string(node.LinkData.Destination),
-				-1, 0, 0, 0, true,
-				gofpdf.ImageOptions{ImageType: "", ReadDpi: true}, 0, "")
-		} else {
-			r.tracer("Image (file error)", err.Error())
+func (r *PdfRenderer) processImage(node *bf.Node, entering bool) bf.WalkStatus {
+	// the children of an image are its alt text, which is used for the
+	// caption rather than being rendered, so they are skipped
+	r.tracer("Image (entering)",
+		fmt.Sprintf("Destination[%v] Title[%v]",
+			string(node.LinkData.Destination),
+			string(node.LinkData.Title)))
+	dest, hints := splitSizeSuffix(string(node.LinkData.Destination))
+	if node.Next != nil && node.Next.Type == bf.Text {
+		// the attributes are left out when the text is written
+		if attrs, _, ok := parseImageAttributes(string(node.Next.Literal)); ok {
+			hints = hints.merge(attrs)
 		}
+	}
+
+	// an image within a link is clickable
+	url := ""
+	if r.cs.peek().containerType == bf.Link {
+		url = r.cs.peek().destination
+	}
+
+	// an image that is alone on its line is a block-level figure;
+	// otherwise it flows with the text around it
+	inline := !isStandaloneImage(node)
+	var resume *textLayout
+	if !inline {
+		// the text before it in the paragraph is written first, and the
+		// text after it starts on a new line; see followsBlockImage
+		resume = r.suspendLayout()
+	}
+
+	img, err := r.loadImage(dest)
+	if err == nil && inline {
+		r.placeInlineImage(img, hints, url)
+	} else if err == nil {
+		caption := r.captionOf(node)
+		align, top := r.placeImage(img, hints, url)
+		r.addFigure(caption, align, top)
 	} else {
-		r.tracer("Image (leaving)", "")
+		r.tracer("Image (file error)", err.Error())
+		if !r.missingImage(dest, plainText(node), hints, inline, err) {
+			return bf.Terminate
+		}
+	}
+	if resume != nil {
+		r.layout = resume
 	}
+	return bf.SkipChildren
 }
 
 func (r *PdfRenderer) processCode(node *bf.Node) {
 	r.tracer("Code", "")
+	if r.layout != nil {
+		r.addText(r.Backtick, string(node.Literal), "")
+		return
+	}
 	r.setStyler(r.Backtick)
 	r.write(r.Backtick, string(node.Literal))
 }
@@ -246,12 +373,15 @@ func (r *PdfRenderer) processParagraph(node *bf.Node, entering bool) {
 					r.cr()
 				}
 			}
+			r.startLayout(r.cs.peek().textStyle, true)
 			return
 		}
 		r.cr()
 		//r.cr()
+		r.startLayout(r.cs.peek().textStyle, true)
 	} else {
 		r.tracer("Paragraph (leaving)", "")
+		r.flushLayout()
 		lm, tm, rm, bm := r.Pdf.GetMargins()
 		r.tracer("... Margins (left, top, right, bottom:",
 			fmt.Sprintf("%v %v %v %v", lm, tm, rm, bm))
@@ -273,18 +403,32 @@ func (r *PdfRenderer) processParagraph(node *bf.Node, entering bool) {
 }
 
 func (r *PdfRenderer) processBlockQuote(node *bf.Node, entering bool) {
+	indent := r.IndentValue
+	if r.QuoteIndent != 0 {
+		indent = r.QuoteIndent
+	}
 	if entering {
 		r.tracer("BlockQuote (entering)", "")
 		curleftmargin, _, _, _ := r.Pdf.GetMargins()
 		x := &containerState{containerType: bf.BlockQuote,
 			textStyle: r.Blockquote, listkind: notlist,
-			leftMargin: curleftmargin + r.IndentValue}
+			leftMargin: curleftmargin + indent,
+			rtl:        r.rtlBlock(plainText(node))}
 		r.cs.push(x)
-		r.Pdf.SetLeftMargin(curleftmargin + r.IndentValue)
+		if x.rtl {
+			x.leftMargin = curleftmargin
+			r.indentRight(indent)
+		} else {
+			r.Pdf.SetLeftMargin(curleftmargin + indent)
+		}
 	} else {
 		r.tracer("BlockQuote (leaving)", "")
-		curleftmargin, _, _, _ := r.Pdf.GetMargins()
-		r.Pdf.SetLeftMargin(curleftmargin - r.IndentValue)
+		if r.cs.peek().rtl {
+			r.indentRight(-indent)
+		} else {
+			curleftmargin, _, _, _ := r.Pdf.GetMargins()
+			r.Pdf.SetLeftMargin(curleftmargin - indent)
+		}
 		r.cs.pop()
 		r.cr()
 	}
@@ -292,7 +436,13 @@ func (r *PdfRenderer) processBlockQuote(node *bf.Node, entering bool) {
 
 func (r *PdfRenderer) processHeading(node *bf.Node, entering bool) {
 	if entering {
+		if node.HeadingData.Level <= r.PageBreakLevel {
+			r.pageBreak()
+		}
 		r.cr()
+		r.keepWithNext(node, *r.headingStyle(node.HeadingData.Level))
+		r.setAnchor(headingAnchor(node))
+		r.tocHeading(node)
 		//r.inHeading = true
 		switch node.HeadingData.Level {
 		case 1:
@@ -332,8 +482,10 @@ func (r *PdfRenderer) processHeading(node *bf.Node, entering bool) {
 				leftMargin: r.cs.peek().leftMargin}
 			r.cs.push(x)
 		}
+		r.startLayout(r.cs.peek().textStyle, false)
 	} else {
 		r.tracer("Heading (leaving)", "")
+		r.flushLayout()
 		r.cr()
 		r.cs.pop()
 	}
@@ -364,6 +516,10 @@ func (r *PdfRenderer) processHorizontalRule(node *bf.Node) {
 
 func (r *PdfRenderer) processHTMLBlock(node *bf.Node) {
 	r.tracer("HTMLBlock", string(node.Literal))
+	if r.isPageBreakHTML(string(node.Literal)) {
+		r.pageBreak()
+		return
+	}
 	r.cr()
 	r.setStyler(r.Backtick)
 	r.Pdf.CellFormat(0, r.Backtick.Size,
@@ -449,7 +605,7 @@ func (r *PdfRenderer) processTableCell(node *bf.Node, entering bool) {
 			textStyle: r.Normal, listkind: notlist,
 			leftMargin: r.cs.peek().leftMargin}
 		if node.TableCellData.IsHeader {
-			r.Pdf.SetDrawColor(128, 0, 0)
+			r.Pdf.SetDrawColor(r.BorderColor.Red, r.BorderColor.Green, r.BorderColor.Blue)
 			r.Pdf.SetLineWidth(.3)
 			x.isHeader = true
 			x.textStyle = r.THeader
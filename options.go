@@ -0,0 +1,292 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/phpdave11/gofpdf"
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// The page orientations.
+const (
+	Portrait  = "portrait"
+	Landscape = "landscape"
+)
+
+// The units that WithUnit accepts, in which the lengths given to
+// WithCustomPaperSize and WithMargins are measured.
+const (
+	Points      = "pt"
+	Millimetres = "mm"
+	Centimetres = "cm"
+	Inches      = "in"
+)
+
+var pointsPer = map[string]float64{
+	Points:      1,
+	Millimetres: 72 / 25.4,
+	Centimetres: 72 / 2.54,
+	Inches:      72,
+}
+
+// Option configures a PdfRenderer made by New.
+type Option func(*settings)
+
+// settings collects the options, which are applied in a fixed order once
+// they are all known: the page format is needed to start the gofpdf
+// document, and the theme, fonts and margins to work out the indentation.
+type settings struct {
+	orientation, paperSize, fontDir string
+	width, height                   float64 // custom paper size, in unit
+	unit                            string
+
+	margins     *Margins // in unit
+	theme       *Theme
+	themeName   string
+	families    [][5]string // name and the four faces
+	fonts       *[3]string
+	tracer      io.Writer
+	extensions  bf.Extensions
+	imageLoader ImageLoader
+	setsLoader  bool
+}
+
+// WithPaperSize chooses a paper size known to gofpdf: "A3", "A4", "A5",
+// "Letter", "Legal" or "Tabloid". The default is A4.
+func WithPaperSize(name string) Option {
+	return func(s *settings) {
+		s.paperSize = name
+		s.width, s.height = 0, 0
+	}
+}
+
+// WithCustomPaperSize sets the width and height of the paper, in portrait
+// orientation, measured in the unit given by WithUnit.
+func WithCustomPaperSize(width, height float64) Option {
+	return func(s *settings) {
+		s.paperSize = ""
+		s.width, s.height = width, height
+	}
+}
+
+// WithOrientation chooses Portrait, the default, or Landscape.
+func WithOrientation(orientation string) Option {
+	return func(s *settings) {
+		s.orientation = orientation
+	}
+}
+
+// WithMargins sets the left, top, right and bottom margins, measured in
+// the unit given by WithUnit. They override the margins of a theme.
+func WithMargins(m Margins) Option {
+	return func(s *settings) {
+		s.margins = &m
+	}
+}
+
+// WithUnit sets the unit of the lengths given to the other options: Points,
+// the default, Millimetres, Centimetres or Inches. It applies to all of
+// them, wherever it comes. Styles, themes and the fields of PdfRenderer are
+// always in points.
+func WithUnit(unit string) Option {
+	return func(s *settings) {
+		s.unit = unit
+	}
+}
+
+// WithFontDir sets the directory that gofpdf and RegisterFontFamily read
+// fonts from. The default is the current directory.
+func WithFontDir(dir string) Option {
+	return func(s *settings) {
+		s.fontDir = dir
+	}
+}
+
+// WithFontFamily registers a family of TrueType fonts, as
+// RegisterFontFamily does, so that WithFonts or a theme can use it.
+func WithFontFamily(name, regular, bold, italic, boldItalic string) Option {
+	return func(s *settings) {
+		s.families = append(s.families, [5]string{name, regular, bold, italic, boldItalic})
+	}
+}
+
+// WithFonts sets the body, heading and monospaced fonts, as SetFonts does.
+// They take effect after any theme.
+func WithFonts(body, heading, mono string) Option {
+	return func(s *settings) {
+		s.fonts = &[3]string{body, heading, mono}
+	}
+}
+
+// WithTheme sets the theme, as SetTheme does.
+func WithTheme(t Theme) Option {
+	return func(s *settings) {
+		s.theme, s.themeName = &t, ""
+	}
+}
+
+// WithThemeName sets a built-in theme by name or else loads a theme file,
+// as UseTheme does.
+func WithThemeName(name string) Option {
+	return func(s *settings) {
+		s.theme, s.themeName = nil, name
+	}
+}
+
+// WithTracer writes a trace of the rendering to w. TracerFile, if it is
+// set, is used instead.
+func WithTracer(w io.Writer) Option {
+	return func(s *settings) {
+		s.tracer = w
+	}
+}
+
+// WithExtensions sets the blackfriday extensions that the markdown is
+// parsed with, e.g. bf.CommonExtensions | bf.Footnotes.
+func WithExtensions(extensions bf.Extensions) Option {
+	return func(s *settings) {
+		s.extensions = extensions
+	}
+}
+
+// WithImageLoader sets the loader of http, https and data URL images;
+// nil disables such images.
+func WithImageLoader(loader ImageLoader) Option {
+	return func(s *settings) {
+		s.imageLoader, s.setsLoader = loader, true
+	}
+}
+
+// New creates a PdfRenderer, which satisfies the BlackFriday Renderer
+// interface, configured by the options. Whatever the options, the options
+// for the page come first, then the theme, the fonts and the margins.
+// Errors from gofpdf, such as an unknown paper size, are returned by
+// ToFile or Output.
+//
+// It is not safe to use instances in more than one goroutine.
+func New(opts ...Option) (*PdfRenderer, error) {
+	s := settings{unit: Points}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	scale, ok := pointsPer[strings.ToLower(s.unit)]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", s.unit)
+	}
+	if s.width < 0 || s.height < 0 || (s.width == 0) != (s.height == 0) {
+		return nil, fmt.Errorf("invalid paper size %gx%g", s.width, s.height)
+	}
+
+	r := new(PdfRenderer)
+
+	// the styles of text, bullets and colours
+	r.applyStyles(DefaultTheme())
+
+	r.EmojiShortcodes = true
+	r.ListOfFiguresTitle = "List of Figures"
+	r.TitleLayout = defaultTitlePageLayout()
+	r.TableOfContentsTitle = "Contents"
+	r.TableOfContentsDepth = 3
+	r.PageBreakComment = "pagebreak"
+
+	r.ImageLoader = NewImageLoader()
+	if s.setsLoader {
+		r.ImageLoader = s.imageLoader
+	}
+	r.Extensions = s.extensions
+	r.traceOut = s.tracer
+
+	r.orientation, r.paperSize, r.fontDir = s.orientation, s.paperSize, s.fontDir
+	r.pageSize = gofpdf.SizeType{Wd: s.width * scale, Ht: s.height * scale}
+	r.Pdf = r.newPdf()
+	// set default font
+	r.setStyler(r.Normal)
+	r.em = r.Pdf.GetStringWidth("m")
+	r.IndentValue = 3 * r.em
+	r.mleft, r.mtop, r.mright, r.mbottom = r.Pdf.GetMargins()
+
+	r.cs = states{stack: make([]*containerState, 0)}
+	initcurrent := &containerState{containerType: bf.Paragraph,
+		listkind:  notlist,
+		textStyle: r.Normal, leftMargin: r.mleft}
+	r.cs.push(initcurrent)
+
+	for _, f := range s.families {
+		if err := r.RegisterFontFamily(f[0], f[1], f[2], f[3], f[4]); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.themeName != "" {
+		t, err := r.findTheme(s.themeName)
+		if err != nil {
+			return nil, err
+		}
+		s.theme = &t
+	}
+	if s.theme != nil {
+		r.SetTheme(*s.theme)
+	}
+
+	if s.fonts != nil {
+		r.SetFonts(s.fonts[0], s.fonts[1], s.fonts[2])
+	}
+
+	if s.margins != nil {
+		m := *s.margins
+		r.setMargins(Margins{Left: m.Left * scale, Top: m.Top * scale, Right: m.Right * scale, Bottom: m.Bottom * scale})
+	}
+
+	r.setStyler(r.Normal)
+	return r, nil
+}
+
+// newPdf starts a gofpdf document in the renderer's page format.
+func (r *PdfRenderer) newPdf() *gofpdf.Fpdf {
+	return gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: r.orientation,
+		UnitStr:        "pt",
+		SizeStr:        r.paperSize,
+		Size:           r.pageSize,
+		FontDirStr:     r.fontDir,
+	})
+}
+
+// startTracer opens the trace, if there is to be one, and gives the
+// function that finishes it.
+func (r *PdfRenderer) startTracer() (func(), error) {
+	switch {
+	case r.TracerFile != "":
+		f, err := os.Create(r.TracerFile)
+		if err != nil {
+			return nil, fmt.Errorf("os.Create() on tracefile: %w", err)
+		}
+		r.traceWriter = bufio.NewWriter(f)
+		return func() {
+			r.traceWriter.Flush()
+			r.traceWriter = nil
+			f.Close()
+		}, nil
+	case r.traceOut != nil:
+		r.traceWriter = bufio.NewWriter(r.traceOut)
+		return func() {
+			r.traceWriter.Flush()
+			r.traceWriter = nil
+		}, nil
+	}
+	return func() {}, nil
+}
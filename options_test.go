@@ -0,0 +1,216 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestNewDefaults(t *testing.T) {
+	r, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := NewPdfRenderer("", "", "")
+	w, h := r.Pdf.GetPageSize()
+	ow, oh := old.Pdf.GetPageSize()
+	if w != ow || h != oh {
+		t.Errorf("page %gx%g, want %gx%g", w, h, ow, oh)
+	}
+	if r.IndentValue != old.IndentValue || !reflect.DeepEqual(r.Normal, old.Normal) {
+		t.Errorf("New() differs from NewPdfRenderer")
+	}
+	if r.Extensions != 0 || r.ImageLoader == nil {
+		t.Errorf("got extensions %v, image loader %v", r.Extensions, r.ImageLoader)
+	}
+}
+
+func TestPageOptions(t *testing.T) {
+	cases := []struct {
+		opts []Option
+		w, h float64
+	}{
+		{[]Option{WithPaperSize("Letter"), WithOrientation(Landscape)}, 792, 612},
+		{[]Option{WithCustomPaperSize(100, 200)}, 100, 200},
+		{[]Option{WithCustomPaperSize(210, 297), WithUnit(Millimetres)}, 595.28, 841.89},
+		{[]Option{WithUnit("in"), WithCustomPaperSize(4, 6), WithOrientation(Landscape)}, 432, 288},
+		// the last paper size wins
+		{[]Option{WithCustomPaperSize(100, 200), WithPaperSize("A5")}, 420.94, 595.28},
+	}
+	for i, c := range cases {
+		r, err := New(c.opts...)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if w, h := r.Pdf.GetPageSize(); !near(w, c.w) || !near(h, c.h) {
+			t.Errorf("%d: page %gx%g, want %gx%g", i, w, h, c.w, c.h)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	cases := [][]Option{
+		{WithUnit("furlong")},
+		{WithCustomPaperSize(100, 0)},
+		{WithCustomPaperSize(-100, 100)},
+		{WithThemeName("no-such-theme.yaml")},
+		{WithFontFamily("Missing", "missing.ttf", "", "", "")},
+	}
+	for i, opts := range cases {
+		if r, err := New(opts...); err == nil || r != nil {
+			t.Errorf("%d: want an error", i)
+		}
+	}
+}
+
+func TestCustomSizeKeptByFrontMatter(t *testing.T) {
+	r, err := New(WithCustomPaperSize(100, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Process([]byte("---\norientation: landscape\n---\n\ntext\n")).Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if w, h := r.Pdf.GetPageSize(); !near(w, 200) || !near(h, 100) {
+		t.Errorf("page %gx%g, want 200x100", w, h)
+	}
+
+	r, err = New(WithCustomPaperSize(100, 200), WithOrientation(Landscape))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Process([]byte("---\npaper: A4\n---\n\ntext\n")).Output(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := r.Pdf.GetPageSize(); !near(w, 841.89) {
+		t.Errorf("page width %g, want A4 landscape", w)
+	}
+}
+
+func TestMarginOptions(t *testing.T) {
+	theme := DefaultTheme()
+	theme.Margins = Margins{Left: 10, Top: 10, Right: 10, Bottom: 10}
+	theme.Indent = 30
+
+	// the margins override the theme's, wherever they come
+	r, err := New(WithMargins(Margins{Left: 2, Top: 1, Right: 2, Bottom: 1.5}), WithUnit(Centimetres), WithTheme(theme))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lm, tm, rm, _ := r.Pdf.GetMargins()
+	_, bm := r.Pdf.GetAutoPageBreak()
+	if !near(lm, 56.69) || !near(tm, 28.35) || !near(rm, 56.69) || !near(bm, 42.52) {
+		t.Errorf("margins %g %g %g %g", lm, tm, rm, bm)
+	}
+	if r.cs.stack[0].leftMargin != lm || r.Pdf.GetX() != lm {
+		t.Errorf("the text doesn't start at the left margin")
+	}
+	if r.IndentValue != 30 {
+		t.Errorf("indent %g, want the theme's", r.IndentValue)
+	}
+}
+
+func TestThemeAndFontOptions(t *testing.T) {
+	r, err := New(WithFonts("Times", "", "Helvetica"), WithThemeName("dark"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark, _ := BuiltinTheme("dark")
+	if r.IndentValue != dark.Indent {
+		t.Errorf("indent %g, want the theme's %g", r.IndentValue, dark.Indent)
+	}
+	if r.Normal.Font != "Times" || r.Backtick.Font != "Helvetica" || r.H1.Font != dark.H1.Font {
+		t.Errorf("got fonts %q %q %q", r.Normal.Font, r.H1.Font, r.Backtick.Font)
+	}
+	if r.Normal.TextColor != dark.Normal.TextColor {
+		t.Errorf("got text colour %v, want the theme's", r.Normal.TextColor)
+	}
+
+	// without one from the theme, the indentation follows the body font
+	dark.Indent = 0
+	r, err = New(WithTheme(dark), WithFonts("Times", "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.setStyler(r.Normal)
+	if want := 3 * r.Pdf.GetStringWidth("m"); !near(r.IndentValue, want) {
+		t.Errorf("indent %g, want %g", r.IndentValue, want)
+	}
+}
+
+func TestFontFamilyOption(t *testing.T) {
+	needDejaVu(t)
+	r, err := New(WithFontDir(dejaVuDir),
+		WithFontFamily("DejaVu", "DejaVuSans.ttf", "DejaVuSans-Bold.ttf", "", ""),
+		WithFonts("DejaVu", "DejaVu", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Normal.Font != "DejaVu" {
+		t.Errorf("got font %q", r.Normal.Font)
+	}
+	old := NewPdfRenderer("", "", "")
+	if r.IndentValue == old.IndentValue {
+		t.Errorf("indent %g wasn't worked out for DejaVu", r.IndentValue)
+	}
+}
+
+func TestTracerOption(t *testing.T) {
+	var trace bytes.Buffer
+	r, err := New(WithTracer(&trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Process([]byte("# Title\n\ntext\n")).Output(new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(trace.String(), "Heading") {
+		t.Errorf("got trace %q", trace.String())
+	}
+	if r.traceWriter != nil {
+		t.Errorf("the trace was left open")
+	}
+}
+
+func TestExtensionsOption(t *testing.T) {
+	md := []byte("a | b\n---|---\n1 | 2\n")
+	for _, c := range []struct {
+		ext   bf.Extensions
+		table bool
+	}{
+		{0, true},
+		{bf.Tables, true},
+		{bf.Autolink, false},
+	} {
+		var trace bytes.Buffer
+		r, err := New(WithExtensions(c.ext), WithTracer(&trace))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Process(md).Output(new(bytes.Buffer)); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(trace.String(), "[Table (entering)]"); got != c.table {
+			t.Errorf("extensions %v: table %v, want %v", c.ext, got, c.table)
+		}
+	}
+}
+
+func TestImageLoaderOption(t *testing.T) {
+	r, err := New(WithImageLoader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ImageLoader != nil {
+		t.Errorf("got %v, want no loader", r.ImageLoader)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// fits reports whether a block of height h will fit below the cursor on
+// the current page. Anything fits at the top of a page, because breaking
+// the page there would not help.
+func (r *PdfRenderer) fits(h float64) bool {
+	y := r.Pdf.GetY()
+	_, tm, _, _ := r.Pdf.GetMargins()
+	_, pageH := r.Pdf.GetPageSize()
+	_, bm := r.Pdf.GetAutoPageBreak()
+	return y <= tm || y+h <= pageH-bm
+}
+
+// pageBreak starts a new page, unless the cursor is already at the top of
+// one.
+func (r *PdfRenderer) pageBreak() {
+	_, tm, _, _ := r.Pdf.GetMargins()
+	if r.Pdf.GetY() <= tm {
+		return
+	}
+	r.tracer("... Page break", "")
+	r.addPage()
+}
+
+// isPageBreakParagraph reports whether a paragraph is just the LaTeX-style
+// command \pagebreak or \newpage.
+func isPageBreakParagraph(node *bf.Node) bool {
+	child := node.FirstChild
+	if child == nil || child.Next != nil || child.Type != bf.Text {
+		return false
+	}
+	text := strings.TrimSpace(string(child.Literal))
+	return text == `\pagebreak` || text == `\newpage`
+}
+
+// pageBreakDiv matches an empty div styled to break the page, as in
+// <div style="page-break-after: always"></div>.
+var pageBreakDiv = regexp.MustCompile(`(?is)^<div\b[^>]*\bstyle\s*=\s*["'][^"']*\b(page-)?break-(before|after)\s*:\s*(always|page)\b[^>]*>\s*</div>$`)
+
+// isPageBreakHTML reports whether an HTML block is a page break marker:
+// either a styled div or the PageBreakComment.
+func (r *PdfRenderer) isPageBreakHTML(html string) bool {
+	html = strings.TrimSpace(html)
+	if pageBreakDiv.MatchString(html) {
+		return true
+	}
+	if r.PageBreakComment == "" || len(html) < 7 || !strings.HasPrefix(html, "<!--") || !strings.HasSuffix(html, "-->") {
+		return false
+	}
+	comment := strings.TrimSpace(html[4 : len(html)-3])
+	return strings.EqualFold(comment, strings.TrimSpace(r.PageBreakComment))
+}
+
+// keepWithNext starts a new page before a heading unless the heading and
+// the first KeepWithNext lines of the block after it fit on this one.
+func (r *PdfRenderer) keepWithNext(node *bf.Node, s Styler) {
+	if r.KeepWithNext <= 0 {
+		return
+	}
+
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	r.setStyler(s)
+	width := r.Pdf.GetStringWidth(plainText(node))
+	lines := math.Max(1, math.Ceil(width/(pageW-lm-rm)))
+	need := lines * (s.Size + s.Spacing)
+
+	if next := r.styleOfBlock(node.Next); next != nil {
+		// the blank line before the block, then its first lines
+		need += r.Normal.Size + r.Normal.Spacing
+		need += float64(r.KeepWithNext) * (next.Size + next.Spacing)
+	}
+
+	if !r.fits(need) {
+		r.tracer("... Keep with next", fmt.Sprintf("%.1f needed; new page", need))
+		r.addPage()
+	}
+	r.setStyler(r.cs.peek().textStyle)
+}
+
+// styleOfBlock gives the style of the text at the start of a block, or nil
+// if there is no block.
+func (r *PdfRenderer) styleOfBlock(node *bf.Node) *Styler {
+	if node == nil {
+		return nil
+	}
+	switch node.Type {
+	case bf.CodeBlock:
+		return &r.Backtick
+	case bf.BlockQuote:
+		return &r.Blockquote
+	case bf.Table:
+		return &r.THeader
+	case bf.Heading:
+		return r.headingStyle(node.HeadingData.Level)
+	}
+	return &r.Normal
+}
+
+// headingStyle gives the style for a heading level.
+func (r *PdfRenderer) headingStyle(level int) *Styler {
+	switch level {
+	case 1:
+		return &r.H1
+	case 2:
+		return &r.H2
+	case 3:
+		return &r.H3
+	case 4:
+		return &r.H4
+	case 5:
+		return &r.H5
+	}
+	return &r.H6
+}
+
+// paginate decides where the lines of a paragraph start new pages, given
+// their heights, the position y of the first line and the usable extent of
+// a page. It returns the indexes of the lines that begin a page. Unless the
+// page is too short, at least orphans lines are left at the foot of a page
+// and at least widows lines are carried to the top of the next; failing
+// that, the whole paragraph starts a new page if movable is true.
+func paginate(heights []float64, y, top, bottom float64, orphans, widows int, movable bool) []int {
+	var breaks []int
+	n := len(heights)
+	start := 0
+	for start < n {
+		pageY := y // where this page's share of the paragraph begins
+		end := start
+		for end < n && (y+heights[end] <= bottom || (end == start && y <= top)) {
+			y += heights[end]
+			end++
+		}
+		if end == n {
+			break
+		}
+
+		if start == 0 && movable && pageY > top {
+			if end < orphans {
+				end = 0
+			} else if n-end < widows {
+				if n-widows >= orphans {
+					end = n - widows
+				} else {
+					end = 0
+				}
+			}
+		} else if n-end < widows && n-widows > start {
+			end = n - widows
+		}
+
+		breaks = append(breaks, end)
+		start = end
+		y = top
+		movable = false
+	}
+	return breaks
+}
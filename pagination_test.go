@@ -0,0 +1,116 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeepWithNext(t *testing.T) {
+	dest := regexp.MustCompile(`/Dest \[(\d+) 0 R`)
+
+	// headingPage finds the page of the heading through the link to it from
+	// the table of contents, after n paragraphs of filler
+	headingPage := func(keep, n int) int {
+		r := NewPdfRenderer("", "", "")
+		r.Pdf.SetCompression(false)
+		r.TableOfContents = true
+		r.KeepWithNext = keep
+		md := strings.Repeat("Filler.\n\n", n) + "## Heading\n\nSome text that follows the heading.\n"
+		buf := &bytes.Buffer{}
+		if err := r.Process([]byte(md)).Output(buf); err != nil {
+			t.Fatal(err)
+		}
+		m := dest.FindStringSubmatch(buf.String())
+		if m == nil {
+			t.Fatal("no link to the heading")
+		}
+		obj, _ := strconv.Atoi(m[1])
+		return (obj - 1) / 2 // gofpdf numbers page n as object 1+2n
+	}
+
+	// the last n that leaves the heading at the foot of the first body page
+	first := headingPage(0, 0)
+	n := 1
+	for headingPage(0, n) == first {
+		n++
+	}
+	n--
+
+	if got := headingPage(2, n); got != first+1 {
+		t.Errorf("keep 2: heading on page %d; want %d", got, first+1)
+	}
+	if got := headingPage(2, 0); got != first {
+		t.Errorf("keep 2 at the top: heading on page %d; want %d", got, first)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	lines := func(n int) []float64 {
+		h := make([]float64, n)
+		for i := range h {
+			h[i] = 10
+		}
+		return h
+	}
+
+	// a page holds ten lines between 0 and 100
+	cases := []struct {
+		name            string
+		n               int
+		y               float64
+		orphans, widows int
+		movable         bool
+		want            []int
+	}{
+		{"fits", 5, 50, 2, 2, true, nil},
+		{"no control", 5, 90, 1, 1, true, []int{1}},
+		{"orphan", 5, 90, 2, 2, true, []int{0}},
+		{"orphan not movable", 5, 90, 2, 2, false, []int{1}},
+		{"widow", 5, 60, 2, 2, true, []int{3}},
+		{"widow and orphan", 3, 80, 2, 2, true, []int{0}},
+		{"long", 23, 80, 2, 2, true, []int{2, 12, 21}},
+		{"first line too low", 3, 95, 1, 1, false, []int{0}},
+		{"at top", 12, 0, 3, 3, true, []int{9}},
+	}
+
+	for _, c := range cases {
+		got := paginate(lines(c.n), c.y, 0, 100, c.orphans, c.widows, c.movable)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v; want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPageBreakMarkers(t *testing.T) {
+	cases := []struct {
+		markdown string
+		pages    int
+	}{
+		{"one\n\n\\pagebreak\n\ntwo\n", 2},
+		{"one\n\n\\newpage\n\ntwo\n", 2},
+		{"one \\pagebreak two\n", 1},
+		{"one\n\n<div style=\"page-break-after: always\"></div>\n\ntwo\n", 2},
+		{"one\n\n<div class=\"x\" style='break-before: page'>\n</div>\n\ntwo\n", 2},
+		{"one\n\n<div style=\"page-break-after: auto\"></div>\n\ntwo\n", 1},
+		{"one\n\n<!-- PageBreak -->\n\ntwo\n", 2},
+		{"one\n\n<!-- a comment -->\n\ntwo\n", 1},
+		{"\\pagebreak\n\none\n", 1}, // already at the top of a page
+		{"# One\n\ntext\n\n# Two\n\ntext\n\n## Three\n", 2},
+	}
+
+	for _, c := range cases {
+		r := NewPdfRenderer("", "", "")
+		r.PageBreakLevel = 1
+		if err := r.Process([]byte(c.markdown)).Output(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Pdf.PageNo(); got != c.pages {
+			t.Errorf("%q: got %d pages; want %d", c.markdown, got, c.pages)
+		}
+	}
+}
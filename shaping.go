@@ -0,0 +1,317 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"unicode"
+)
+
+// Some scripts need shaping: the glyph for a character depends on its
+// neighbours. gofpdf embeds fonts by character, not by glyph, so glyphs
+// that the font's substitution tables reach only through ligatures can't be
+// written. Shaping is instead done with characters, in logical order before
+// the text is reordered for writing:
+//
+// Arabic letters are joined by replacing them with their initial, medial,
+// final and isolated presentation forms, and lam followed by alef with the
+// lam-alef ligature, wherever the font has glyphs for those forms.
+//
+// In Indic scripts, vowel signs written before the consonant they follow,
+// such as Devanagari i, are moved in front of its consonant cluster, and
+// two-part vowel signs are split into their parts. Conjuncts and half
+// forms are not made; the consonants are written with a visible virama.
+// GPOS is not read either, so marks are not positioned. The README lists
+// these limits.
+
+// arabicForms are the presentation forms of an Arabic letter: isolated,
+// final, initial and medial. Letters that only join to the letter before
+// them, such as alef, have no initial or medial forms.
+type arabicForms [4]rune
+
+const (
+	isolated = iota
+	final
+	initial
+	medial
+)
+
+func (f arabicForms) dual() bool {
+	return f[initial] != 0
+}
+
+var arabicLetters = map[rune]arabicForms{
+	0x0621: {0xfe80, 0, 0, 0},
+	0x0622: {0xfe81, 0xfe82, 0, 0},
+	0x0623: {0xfe83, 0xfe84, 0, 0},
+	0x0624: {0xfe85, 0xfe86, 0, 0},
+	0x0625: {0xfe87, 0xfe88, 0, 0},
+	0x0626: {0xfe89, 0xfe8a, 0xfe8b, 0xfe8c},
+	0x0627: {0xfe8d, 0xfe8e, 0, 0},
+	0x0628: {0xfe8f, 0xfe90, 0xfe91, 0xfe92},
+	0x0629: {0xfe93, 0xfe94, 0, 0},
+	0x062a: {0xfe95, 0xfe96, 0xfe97, 0xfe98},
+	0x062b: {0xfe99, 0xfe9a, 0xfe9b, 0xfe9c},
+	0x062c: {0xfe9d, 0xfe9e, 0xfe9f, 0xfea0},
+	0x062d: {0xfea1, 0xfea2, 0xfea3, 0xfea4},
+	0x062e: {0xfea5, 0xfea6, 0xfea7, 0xfea8},
+	0x062f: {0xfea9, 0xfeaa, 0, 0},
+	0x0630: {0xfeab, 0xfeac, 0, 0},
+	0x0631: {0xfead, 0xfeae, 0, 0},
+	0x0632: {0xfeaf, 0xfeb0, 0, 0},
+	0x0633: {0xfeb1, 0xfeb2, 0xfeb3, 0xfeb4},
+	0x0634: {0xfeb5, 0xfeb6, 0xfeb7, 0xfeb8},
+	0x0635: {0xfeb9, 0xfeba, 0xfebb, 0xfebc},
+	0x0636: {0xfebd, 0xfebe, 0xfebf, 0xfec0},
+	0x0637: {0xfec1, 0xfec2, 0xfec3, 0xfec4},
+	0x0638: {0xfec5, 0xfec6, 0xfec7, 0xfec8},
+	0x0639: {0xfec9, 0xfeca, 0xfecb, 0xfecc},
+	0x063a: {0xfecd, 0xfece, 0xfecf, 0xfed0},
+	0x0640: {0x0640, 0x0640, 0x0640, 0x0640}, // tatweel
+	0x0641: {0xfed1, 0xfed2, 0xfed3, 0xfed4},
+	0x0642: {0xfed5, 0xfed6, 0xfed7, 0xfed8},
+	0x0643: {0xfed9, 0xfeda, 0xfedb, 0xfedc},
+	0x0644: {0xfedd, 0xfede, 0xfedf, 0xfee0},
+	0x0645: {0xfee1, 0xfee2, 0xfee3, 0xfee4},
+	0x0646: {0xfee5, 0xfee6, 0xfee7, 0xfee8},
+	0x0647: {0xfee9, 0xfeea, 0xfeeb, 0xfeec},
+	0x0648: {0xfeed, 0xfeee, 0, 0},
+	0x0649: {0xfeef, 0xfef0, 0, 0},
+	0x064a: {0xfef1, 0xfef2, 0xfef3, 0xfef4},
+	// letters for Persian and Urdu
+	0x0671: {0xfb50, 0xfb51, 0, 0},
+	0x0679: {0xfb66, 0xfb67, 0xfb68, 0xfb69},
+	0x067e: {0xfb56, 0xfb57, 0xfb58, 0xfb59},
+	0x0686: {0xfb7a, 0xfb7b, 0xfb7c, 0xfb7d},
+	0x0688: {0xfb88, 0xfb89, 0, 0},
+	0x0691: {0xfb8c, 0xfb8d, 0, 0},
+	0x0698: {0xfb8a, 0xfb8b, 0, 0},
+	0x06a9: {0xfb8e, 0xfb8f, 0xfb90, 0xfb91},
+	0x06af: {0xfb92, 0xfb93, 0xfb94, 0xfb95},
+	0x06ba: {0xfb9e, 0xfb9f, 0, 0},
+	0x06be: {0xfbaa, 0xfbab, 0xfbac, 0xfbad},
+	0x06c1: {0xfba6, 0xfba7, 0xfba8, 0xfba9},
+	0x06cc: {0xfbfc, 0xfbfd, 0xfbfe, 0xfbff},
+	0x06d2: {0xfbae, 0xfbaf, 0, 0},
+}
+
+// lamAlef gives the isolated and final forms of the ligature of lam with
+// each kind of alef.
+var lamAlef = map[rune][2]rune{
+	0x0622: {0xfef5, 0xfef6},
+	0x0623: {0xfef7, 0xfef8},
+	0x0625: {0xfef9, 0xfefa},
+	0x0627: {0xfefb, 0xfefc},
+}
+
+const (
+	lam = 0x0644
+	zwj = 0x200d // zero width joiner
+)
+
+// transparent characters, such as the short vowel marks, don't affect
+// joining.
+func transparent(ch rune) bool {
+	return unicode.In(ch, unicode.Mn, unicode.Me)
+}
+
+// needsShaping reports whether text has any Arabic or Indic characters.
+func needsShaping(text string) bool {
+	for _, ch := range text {
+		if (ch >= 0x0600 && ch <= 0x06ff) || (ch >= 0x0900 && ch <= 0x0d7f) {
+			return true
+		}
+	}
+	return false
+}
+
+// shapeText shapes Arabic and Indic text. has reports whether a
+// presentation form can be written; forms that can't are left alone.
+func shapeText(text string, has func(rune) bool) string {
+	if !needsShaping(text) {
+		return text
+	}
+	return string(joinArabic(reorderIndic([]rune(text)), has))
+}
+
+// joinArabic replaces Arabic letters by the forms that join them to their
+// neighbours.
+func joinArabic(chars []rune, has func(rune) bool) []rune {
+	// the neighbours of i that take part in joining, skipping marks
+	neighbour := func(i, step int) (arabicForms, bool) {
+		for j := i + step; j >= 0 && j < len(chars); j += step {
+			if transparent(chars[j]) {
+				continue
+			}
+			if chars[j] == zwj {
+				return arabicForms{zwj, zwj, zwj, zwj}, true
+			}
+			f, ok := arabicLetters[chars[j]]
+			return f, ok
+		}
+		return arabicForms{}, false
+	}
+
+	out := make([]rune, 0, len(chars))
+	for i := 0; i < len(chars); i++ {
+		ch := chars[i]
+		forms, ok := arabicLetters[ch]
+		if !ok {
+			out = append(out, ch)
+			continue
+		}
+		prev, hasPrev := neighbour(i, -1)
+		joinsBefore := hasPrev && prev.dual()
+
+		if ch == lam {
+			// the alef may follow marks on the lam, which go after the
+			// ligature
+			j := i + 1
+			for j < len(chars) && transparent(chars[j]) {
+				j++
+			}
+			if j < len(chars) {
+				if lig, ok := lamAlef[chars[j]]; ok {
+					form := lig[isolated]
+					if joinsBefore {
+						form = lig[final]
+					}
+					if has(form) {
+						out = append(out, form)
+						out = append(out, chars[i+1:j]...)
+						i = j
+						continue
+					}
+				}
+			}
+		}
+
+		next, hasNext := neighbour(i, 1)
+		joinsAfter := forms.dual() && hasNext && next[final] != 0
+
+		form := isolated
+		switch {
+		case joinsBefore && joinsAfter:
+			form = medial
+		case joinsBefore:
+			form = final
+		case joinsAfter:
+			form = initial
+		}
+		if shaped := forms[form]; shaped != 0 && has(shaped) {
+			ch = shaped
+		}
+		out = append(out, ch)
+	}
+	return out
+}
+
+// preBaseMatras are the Indic vowel signs written before their consonant.
+var preBaseMatras = map[rune]bool{
+	0x093f: true,                             // Devanagari i
+	0x09bf: true, 0x09c7: true, 0x09c8: true, // Bengali i, e, ai
+	0x0a3f: true,                             // Gurmukhi i
+	0x0abf: true,                             // Gujarati i
+	0x0b47: true,                             // Oriya e
+	0x0bc6: true, 0x0bc7: true, 0x0bc8: true, // Tamil e, ee, ai
+	0x0d46: true, 0x0d47: true, 0x0d48: true, // Malayalam e, ee, ai
+}
+
+// splitMatras are the two-part Indic vowel signs, written on both sides of
+// their consonant.
+var splitMatras = map[rune][2]rune{
+	0x09cb: {0x09c7, 0x09be}, 0x09cc: {0x09c7, 0x09d7},
+	0x0b48: {0x0b47, 0x0b56}, 0x0b4b: {0x0b47, 0x0b3e}, 0x0b4c: {0x0b47, 0x0b57},
+	0x0bca: {0x0bc6, 0x0bbe}, 0x0bcb: {0x0bc7, 0x0bbe}, 0x0bcc: {0x0bc6, 0x0bd7},
+	0x0d4a: {0x0d46, 0x0d3e}, 0x0d4b: {0x0d47, 0x0d3e}, 0x0d4c: {0x0d46, 0x0d57},
+}
+
+// The Indic blocks share a layout, inherited from ISCII: each is 128
+// characters, with the consonants, nukta and virama at the same offsets.
+func indicOffset(ch rune) (rune, bool) {
+	if ch < 0x0900 || ch > 0x0d7f {
+		return 0, false
+	}
+	return (ch - 0x0900) % 0x80, true
+}
+
+func isConsonant(ch rune) bool {
+	off, ok := indicOffset(ch)
+	return ok && unicode.IsLetter(ch) && ((off >= 0x15 && off <= 0x39) || (off >= 0x58 && off <= 0x5f))
+}
+
+func isNukta(ch rune) bool {
+	off, ok := indicOffset(ch)
+	return ok && off == 0x3c
+}
+
+func isVirama(ch rune) bool {
+	off, ok := indicOffset(ch)
+	return ok && off == 0x4d
+}
+
+// reorderIndic moves each pre-base vowel sign in front of the consonant
+// cluster it follows, a cluster being consonants joined by viramas.
+func reorderIndic(chars []rune) []rune {
+	out := make([]rune, 0, len(chars))
+	for _, ch := range chars {
+		parts, split := splitMatras[ch]
+		if split {
+			ch = parts[0]
+		}
+		if preBaseMatras[ch] {
+			at := clusterStart(out)
+			out = append(out, 0)
+			copy(out[at+1:], out[at:])
+			out[at] = ch
+		} else {
+			out = append(out, ch)
+		}
+		if split {
+			out = append(out, parts[1])
+		}
+	}
+	return out
+}
+
+// clusterStart finds where the consonant cluster at the end of chars
+// begins, or gives len(chars) if it doesn't end with one.
+func clusterStart(chars []rune) int {
+	i := len(chars)
+	if i > 0 && isNukta(chars[i-1]) {
+		i--
+	}
+	if i == 0 || !isConsonant(chars[i-1]) {
+		return len(chars)
+	}
+	i--
+	for i >= 2 && isVirama(chars[i-1]) {
+		j := i - 2
+		if j > 0 && isNukta(chars[j]) {
+			j--
+		}
+		if !isConsonant(chars[j]) {
+			break
+		}
+		i = j
+	}
+	return i
+}
+
+// shape shapes text for writing in the given style, using the forms that
+// its fonts have glyphs for.
+func (r *PdfRenderer) shape(s Styler, text string) string {
+	if !needsShaping(text) {
+		return text
+	}
+	chain := fontChain(s)
+	return shapeText(text, func(form rune) bool {
+		return r.hasGlyph(r.fontFor(chain, form), form)
+	})
+}
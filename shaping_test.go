@@ -0,0 +1,57 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShapeText(t *testing.T) {
+	all := func(rune) bool { return true }
+	cases := []struct {
+		text string
+		want []rune
+	}{
+		{"plain", []rune("plain")},
+		// beh, yeh, teh: initial, medial, final
+		{"بيت", []rune{0xfe91, 0xfef4, 0xfe96}},
+		// seen, then lam-alef, which doesn't join to meem
+		{"سلام", []rune{0xfeb3, 0xfefc, 0xfee1}},
+		{"لا", []rune{0xfefb}},
+		// a mark on the lam doesn't stop the ligature
+		{"لَا", []rune{0xfefb, 0x064e}},
+		{"سلَام", []rune{0xfeb3, 0xfefc, 0x064e, 0xfee1}},
+		// a vowel mark doesn't break joining
+		{"بَت", []rune{0xfe91, 0x064e, 0xfe96}},
+		// alef and dal don't join to what follows
+		{"اب د", []rune{0xfe8d, 0xfe8f, ' ', 0xfea9}},
+		// Devanagari i moves in front of its consonant cluster
+		{"कि", []rune{0x093f, 0x0915}},
+		{"स्थिति", []rune{0x093f, 0x0938, 0x094d, 0x0925, 0x093f, 0x0924}},
+		// a Bengali two-part vowel is split around its consonant
+		{"কো", []rune{0x09c7, 0x0995, 0x09be}},
+	}
+	for _, c := range cases {
+		if got := shapeText(c.text, all); got != string(c.want) {
+			t.Errorf("shapeText(%q) = %U, want %U", c.text, []rune(got), c.want)
+		}
+	}
+
+	none := func(rune) bool { return false }
+	if got := shapeText("بيت", none); got != "بيت" {
+		t.Errorf("forms the font lacks were used: %U", []rune(got))
+	}
+}
+
+func TestShapedArabic(t *testing.T) {
+	r := dejaVuRenderer(t)
+	r.Pdf.SetCompression(false)
+	var buf bytes.Buffer
+	err := r.Process([]byte("---\ndir: rtl\n---\n\nسلام\n")).Output(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// written as shaped forms, in visual order
+	if want := utf16Text(string([]rune{0xfee1, 0xfefc, 0xfeb3})); !bytes.Contains(buf.Bytes(), want) {
+		t.Errorf("shaped text not found")
+	}
+}
@@ -0,0 +1,1079 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/phpdave11/gofpdf"
+)
+
+// SVG images are drawn as native PDF vector graphics so that they stay
+// sharp at any zoom. The common drawing elements are supported: path, rect,
+// circle, ellipse, line, polyline, polygon and text, within groups and
+// nested svg elements that may carry transforms. Fills, strokes, opacity,
+// dashes and line caps and joins are honoured. Gradients, patterns,
+// filters, masks, markers, CSS style sheets and <use> references are not;
+// a paint that refers to a gradient uses its fallback colour if it has one.
+
+// svgImage is a parsed SVG document.
+type svgImage struct {
+	width, height float64    // natural size in points
+	viewBox       [4]float64 // min-x, min-y, width, height in user units
+	aspect        string     // the preserveAspectRatio attribute
+	root          *svgElement
+}
+
+// svgElement is a node in the SVG document tree. Character data is held in
+// children named "#text".
+type svgElement struct {
+	name     string
+	attrs    map[string]string
+	children []*svgElement
+	text     string
+}
+
+// isSVG tests whether image data is an SVG document, from its name or,
+// failing that, its content.
+func isSVG(name string, data []byte) bool {
+	if strings.EqualFold(path.Ext(name), ".svg") {
+		return true
+	}
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return bytes.Contains(head, []byte("<svg"))
+}
+
+// parseSVG reads an SVG document.
+func parseSVG(data []byte) (*svgImage, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	var root *svgElement
+	var stack []*svgElement
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("svg: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &svgElement{name: t.Name.Local, attrs: make(map[string]string)}
+			for _, a := range t.Attr {
+				e.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, &svgElement{name: "#text", text: string(t)})
+			}
+		}
+	}
+
+	if root == nil || root.name != "svg" {
+		return nil, fmt.Errorf("svg: no <svg> element")
+	}
+
+	img := &svgImage{root: root, aspect: root.attrs["preserveAspectRatio"]}
+	vb := parseNumbers(root.attrs["viewBox"])
+	hasViewBox := len(vb) == 4 && vb[2] > 0 && vb[3] > 0
+	if hasViewBox {
+		copy(img.viewBox[:], vb)
+	}
+
+	// the natural size comes from the width and height, or else the
+	// viewBox, or else the HTML default of 300 by 150 pixels
+	w, wok := svgSize(root.attrs["width"])
+	h, hok := svgSize(root.attrs["height"])
+	switch {
+	case wok && hok:
+	case hasViewBox && wok:
+		h = w * img.viewBox[3] / img.viewBox[2]
+	case hasViewBox && hok:
+		w = h * img.viewBox[2] / img.viewBox[3]
+	case hasViewBox:
+		w, h = img.viewBox[2]*0.75, img.viewBox[3]*0.75
+	default:
+		w, h = 225, 112.5
+	}
+	img.width, img.height = w, h
+
+	if !hasViewBox {
+		img.viewBox = [4]float64{0, 0, w / 0.75, h / 0.75}
+	}
+	return img, nil
+}
+
+// svgSize converts an absolute width or height attribute to points. It
+// fails for percentages and missing values.
+func svgSize(s string) (float64, bool) {
+	if s == "" || strings.HasSuffix(s, "%") {
+		return 0, false
+	}
+	pts, _, ok := parseLength(s)
+	return pts, ok && pts > 0
+}
+
+func (img *svgImage) extent() (w, h float64) {
+	return img.width, img.height
+}
+
+// draw maps the viewBox onto the box on the page, honouring
+// preserveAspectRatio, and draws the document clipped to the box.
+func (img *svgImage) draw(r *PdfRenderer, x, y, w, h float64) {
+	vb := img.viewBox
+	sx, sy := w/vb[2], h/vb[3]
+	tx, ty := x, y
+
+	fields := strings.Fields(img.aspect)
+	align, slice := "xMidYMid", false
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	if len(fields) > 1 {
+		slice = fields[1] == "slice"
+	}
+
+	if align != "none" {
+		s := math.Min(sx, sy)
+		if slice {
+			s = math.Max(sx, sy)
+		}
+		switch {
+		case strings.Contains(align, "xMid"):
+			tx += (w - vb[2]*s) / 2
+		case strings.Contains(align, "xMax"):
+			tx += w - vb[2]*s
+		}
+		switch {
+		case strings.Contains(align, "YMid"):
+			ty += (h - vb[3]*s) / 2
+		case strings.Contains(align, "YMax"):
+			ty += h - vb[3]*s
+		}
+		sx, sy = s, s
+	}
+
+	ctm := svgMatrix{sx, 0, 0, sy, tx - vb[0]*sx, ty - vb[1]*sy}
+
+	lineWidth := r.Pdf.GetLineWidth()
+	dr, dg, db := r.Pdf.GetDrawColor()
+
+	r.Pdf.ClipRect(x, y, w, h, false)
+	c := &svgCanvas{r: r}
+	c.drawChildren(img.root, ctm, defaultSVGStyle().inherit(img.root))
+	r.Pdf.ClipEnd()
+
+	r.Pdf.SetAlpha(1, "Normal")
+	r.Pdf.SetDashPattern([]float64{}, 0)
+	r.Pdf.SetLineCapStyle("butt")
+	r.Pdf.SetLineJoinStyle("miter")
+	r.Pdf.SetLineWidth(lineWidth)
+	r.Pdf.SetDrawColor(dr, dg, db)
+	r.setStyler(r.cs.peek().textStyle)
+}
+
+// svgMatrix is an affine transform [a b c d e f], mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f).
+type svgMatrix [6]float64
+
+var svgIdentity = svgMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply gives the transform that applies n and then m.
+func (m svgMatrix) multiply(n svgMatrix) svgMatrix {
+	return svgMatrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m svgMatrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// scale gives the mean scale factor, used for line widths and font sizes.
+func (m svgMatrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// parseTransform reads a transform attribute such as
+// "translate(10,20) rotate(45)".
+func parseTransform(s string) svgMatrix {
+	m := svgIdentity
+	for {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], ", \t\n"))
+		args := parseNumbers(s[open+1 : end])
+		s = s[end+1:]
+
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+
+		var t svgMatrix
+		switch name {
+		case "matrix":
+			if len(args) != 6 {
+				continue
+			}
+			copy(t[:], args)
+		case "translate":
+			t = svgMatrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			sx := arg(0, 1)
+			t = svgMatrix{sx, 0, 0, arg(1, sx), 0, 0}
+		case "rotate":
+			a := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			cos, sin := math.Cos(a), math.Sin(a)
+			t = svgMatrix{1, 0, 0, 1, cx, cy}.
+				multiply(svgMatrix{cos, sin, -sin, cos, 0, 0}).
+				multiply(svgMatrix{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			t = svgMatrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			t = svgMatrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			continue
+		}
+		m = m.multiply(t)
+	}
+}
+
+// svgStyle holds the presentation properties in effect for an element.
+type svgStyle struct {
+	fill, stroke        string
+	color               string
+	fillOpacity         float64
+	strokeOpacity       float64
+	opacity             float64
+	strokeWidth         float64
+	fillRule            string
+	lineCap, lineJoin   string
+	dashArray           []float64
+	fontFamily          string
+	fontSize            float64
+	fontWeight          string
+	fontStyle           string
+	textAnchor          string
+	display, visibility string
+}
+
+func defaultSVGStyle() svgStyle {
+	return svgStyle{
+		fill:          "black",
+		stroke:        "none",
+		color:         "black",
+		fillOpacity:   1,
+		strokeOpacity: 1,
+		opacity:       1,
+		strokeWidth:   1,
+		fillRule:      "nonzero",
+		lineCap:       "butt",
+		lineJoin:      "miter",
+		fontFamily:    "sans-serif",
+		fontSize:      16,
+		textAnchor:    "start",
+		visibility:    "visible",
+	}
+}
+
+// inherit works out the style of an element from its parent's style, its
+// presentation attributes and its style attribute, in increasing priority.
+func (s svgStyle) inherit(e *svgElement) svgStyle {
+	// display and opacity are not inherited, but opacity accumulates
+	s.display = ""
+	props := make(map[string]string)
+	for k, v := range e.attrs {
+		props[k] = v
+	}
+	for _, decl := range strings.Split(e.attrs["style"], ";") {
+		kv := strings.SplitN(decl, ":", 2)
+		if len(kv) == 2 {
+			props[strings.TrimSpace(kv[0])] = strings.TrimSpace(strings.TrimSuffix(kv[1], "!important"))
+		}
+	}
+
+	number := func(v string, def float64) float64 {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return def
+		}
+		return math.Max(0, math.Min(1, f))
+	}
+
+	for k, v := range props {
+		v = strings.TrimSpace(v)
+		if v == "inherit" {
+			continue
+		}
+		switch k {
+		case "fill":
+			s.fill = v
+		case "stroke":
+			s.stroke = v
+		case "color":
+			s.color = v
+		case "fill-opacity":
+			s.fillOpacity = number(v, s.fillOpacity)
+		case "stroke-opacity":
+			s.strokeOpacity = number(v, s.strokeOpacity)
+		case "opacity":
+			s.opacity *= number(v, 1)
+		case "stroke-width":
+			if w, ok := svgLength(v, 0); ok {
+				s.strokeWidth = w
+			}
+		case "fill-rule":
+			s.fillRule = v
+		case "stroke-linecap":
+			s.lineCap = v
+		case "stroke-linejoin":
+			s.lineJoin = v
+		case "stroke-dasharray":
+			if v == "none" {
+				s.dashArray = nil
+			} else {
+				s.dashArray = parseNumbers(v)
+			}
+		case "font-family":
+			s.fontFamily = v
+		case "font-size":
+			if sz, ok := svgLength(v, s.fontSize); ok {
+				s.fontSize = sz
+			}
+		case "font-weight":
+			s.fontWeight = v
+		case "font-style":
+			s.fontStyle = v
+		case "text-anchor":
+			s.textAnchor = v
+		case "display":
+			s.display = v
+		case "visibility":
+			s.visibility = v
+		}
+	}
+	return s
+}
+
+// paint converts a fill or stroke value to a colour and its alpha; ok is
+// false for "none" and for paints that can't be drawn.
+func (s svgStyle) paint(v string) (Color, float64, bool) {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "url(") {
+		// gradients and patterns are not supported; use the fallback
+		end := strings.IndexByte(v, ')')
+		if end < 0 {
+			return Color{}, 0, false
+		}
+		v = strings.TrimSpace(v[end+1:])
+	}
+	switch strings.ToLower(v) {
+	case "", "none", "transparent":
+		return Color{}, 0, false
+	case "currentcolor":
+		v = s.color
+	}
+	c, alpha, err := parseColor(v)
+	return c, alpha, err == nil && alpha > 0
+}
+
+// svgCanvas draws SVG elements on the PDF.
+type svgCanvas struct {
+	r *PdfRenderer
+}
+
+func (c *svgCanvas) drawChildren(e *svgElement, ctm svgMatrix, style svgStyle) {
+	for _, child := range e.children {
+		c.drawElement(child, ctm, style)
+	}
+}
+
+func (c *svgCanvas) drawElement(e *svgElement, ctm svgMatrix, parent svgStyle) {
+	switch e.name {
+	case "#text", "defs", "title", "desc", "metadata", "style", "script",
+		"clipPath", "mask", "marker", "pattern", "symbol",
+		"linearGradient", "radialGradient", "filter":
+		return
+	}
+
+	style := parent.inherit(e)
+	if style.display == "none" {
+		return
+	}
+	if t, ok := e.attrs["transform"]; ok {
+		ctm = ctm.multiply(parseTransform(t))
+	}
+
+	attr := func(name string) float64 {
+		v, _ := svgLength(e.attrs[name], 0)
+		return v
+	}
+
+	var p svgPath
+	switch e.name {
+	case "g", "a", "switch":
+		c.drawChildren(e, ctm, style)
+		return
+	case "svg":
+		// a nested viewport: position it and map its viewBox onto it
+		ctm = ctm.multiply(svgMatrix{1, 0, 0, 1, attr("x"), attr("y")})
+		vb := parseNumbers(e.attrs["viewBox"])
+		w, h := attr("width"), attr("height")
+		if len(vb) == 4 && vb[2] > 0 && vb[3] > 0 && w > 0 && h > 0 {
+			s := math.Min(w/vb[2], h/vb[3])
+			ctm = ctm.multiply(svgMatrix{s, 0, 0, s, -vb[0] * s, -vb[1] * s})
+		}
+		c.drawChildren(e, ctm, style)
+		return
+	case "text":
+		c.drawText(e, ctm, style)
+		return
+	case "path":
+		p = parsePathData(e.attrs["d"])
+	case "rect":
+		p = rectPath(attr("x"), attr("y"), attr("width"), attr("height"), e.attrs["rx"], e.attrs["ry"])
+	case "circle":
+		p = ellipsePath(attr("cx"), attr("cy"), attr("r"), attr("r"))
+	case "ellipse":
+		p = ellipsePath(attr("cx"), attr("cy"), attr("rx"), attr("ry"))
+	case "line":
+		p.moveTo(attr("x1"), attr("y1"))
+		p.lineTo(attr("x2"), attr("y2"))
+		style.fill = "none"
+	case "polyline", "polygon":
+		pts := parseNumbers(e.attrs["points"])
+		for i := 0; i+1 < len(pts); i += 2 {
+			if i == 0 {
+				p.moveTo(pts[i], pts[i+1])
+			} else {
+				p.lineTo(pts[i], pts[i+1])
+			}
+		}
+		if e.name == "polygon" {
+			p.close()
+		}
+	default:
+		return
+	}
+
+	if style.visibility != "hidden" {
+		c.drawPath(p, ctm, style)
+	}
+}
+
+// drawPath fills and then strokes a path according to the style.
+func (c *svgCanvas) drawPath(p svgPath, ctm svgMatrix, style svgStyle) {
+	if len(p) == 0 {
+		return
+	}
+	pdf := c.r.Pdf
+
+	fill, fillAlpha, doFill := style.paint(style.fill)
+	stroke, strokeAlpha, doStroke := style.paint(style.stroke)
+	doStroke = doStroke && style.strokeWidth > 0
+	fillAlpha *= style.opacity * style.fillOpacity
+	strokeAlpha *= style.opacity * style.strokeOpacity
+
+	if doFill {
+		pdf.SetFillColor(fill.Red, fill.Green, fill.Blue)
+	}
+	if doStroke {
+		scale := ctm.scale()
+		pdf.SetDrawColor(stroke.Red, stroke.Green, stroke.Blue)
+		pdf.SetLineWidth(style.strokeWidth * scale)
+		pdf.SetLineCapStyle(style.lineCap)
+		pdf.SetLineJoinStyle(style.lineJoin)
+		if len(style.dashArray) > 0 {
+			dashes := make([]float64, len(style.dashArray))
+			for i, d := range style.dashArray {
+				dashes[i] = d * scale
+			}
+			pdf.SetDashPattern(dashes, 0)
+		} else {
+			pdf.SetDashPattern([]float64{}, 0)
+		}
+	}
+
+	fillOp := "F"
+	if style.fillRule == "evenodd" {
+		fillOp = "F*"
+	}
+
+	switch {
+	case doFill && doStroke && fillAlpha == strokeAlpha:
+		pdf.SetAlpha(fillAlpha, "Normal")
+		p.emit(pdf, ctm)
+		pdf.DrawPath(strings.Replace(fillOp, "F", "FD", 1))
+	default:
+		if doFill {
+			pdf.SetAlpha(fillAlpha, "Normal")
+			p.emit(pdf, ctm)
+			pdf.DrawPath(fillOp)
+		}
+		if doStroke {
+			pdf.SetAlpha(strokeAlpha, "Normal")
+			p.emit(pdf, ctm)
+			pdf.DrawPath("D")
+		}
+	}
+}
+
+// drawText writes a text element, including the content of any tspan
+// elements within it, at the position of the text element.
+func (c *svgCanvas) drawText(e *svgElement, ctm svgMatrix, style svgStyle) {
+	text := strings.Join(strings.Fields(svgCharData(e)), " ")
+	colour, alpha, ok := style.paint(style.fill)
+	if text == "" || !ok || style.visibility == "hidden" {
+		return
+	}
+
+	xs, ys := parseNumbers(e.attrs["x"]), parseNumbers(e.attrs["y"])
+	x, y := 0.0, 0.0
+	if len(xs) > 0 {
+		x = xs[0]
+	}
+	if len(ys) > 0 {
+		y = ys[0]
+	}
+	dxs, dys := parseNumbers(e.attrs["dx"]), parseNumbers(e.attrs["dy"])
+	if len(dxs) > 0 {
+		x += dxs[0]
+	}
+	if len(dys) > 0 {
+		y += dys[0]
+	}
+
+	pdf := c.r.Pdf
+	font := sansFont
+	family := strings.ToLower(style.fontFamily)
+	switch {
+	case strings.Contains(family, "mono") || strings.Contains(family, "courier"):
+		font = monoFont
+	case strings.Contains(family, "sans") || strings.Contains(family, "arial") || strings.Contains(family, "helvetica"):
+		font = sansFont
+	case strings.Contains(family, "serif") || strings.Contains(family, "times"):
+		font = serifFont
+	}
+	fontStyle := ""
+	if w, err := strconv.Atoi(style.fontWeight); style.fontWeight == "bold" || style.fontWeight == "bolder" || (err == nil && w >= 600) {
+		fontStyle += "B"
+	}
+	if style.fontStyle == "italic" || style.fontStyle == "oblique" {
+		fontStyle += "I"
+	}
+
+	size := style.fontSize * ctm.scale()
+	pdf.SetFont(font, fontStyle, size)
+	pdf.SetTextColor(colour.Red, colour.Green, colour.Blue)
+	pdf.SetAlpha(alpha*style.opacity*style.fillOpacity, "Normal")
+
+	px, py := ctm.apply(x, y)
+	width := pdf.GetStringWidth(text)
+	angle := math.Atan2(ctm[1], ctm[0])
+	shift := 0.0
+	switch style.textAnchor {
+	case "middle":
+		shift = width / 2
+	case "end":
+		shift = width
+	}
+
+	if math.Abs(angle) > 1e-6 {
+		pdf.TransformBegin()
+		pdf.TransformRotate(-angle*180/math.Pi, px, py)
+		pdf.Text(px-shift, py, text)
+		pdf.TransformEnd()
+	} else {
+		pdf.Text(px-shift, py, text)
+	}
+}
+
+// svgCharData concatenates the character data within an element.
+func svgCharData(e *svgElement) string {
+	if e.name == "#text" {
+		return e.text
+	}
+	var sb strings.Builder
+	for _, child := range e.children {
+		sb.WriteString(svgCharData(child))
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// svgPath is a path in user space, made only of moves, lines, cubic curves
+// and closes. Other SVG path commands are converted to these.
+type svgPath []svgSegment
+
+type svgSegment struct {
+	op  byte // 'M', 'L', 'C' or 'Z'
+	pts []float64
+}
+
+func (p *svgPath) moveTo(x, y float64) {
+	*p = append(*p, svgSegment{'M', []float64{x, y}})
+}
+
+func (p *svgPath) lineTo(x, y float64) {
+	*p = append(*p, svgSegment{'L', []float64{x, y}})
+}
+
+func (p *svgPath) curveTo(x1, y1, x2, y2, x, y float64) {
+	*p = append(*p, svgSegment{'C', []float64{x1, y1, x2, y2, x, y}})
+}
+
+func (p *svgPath) close() {
+	*p = append(*p, svgSegment{op: 'Z'})
+}
+
+// arcTo adds an elliptical arc, as in the SVG "A" command, from (x1, y1)
+// to (x2, y2), approximated by cubic Béziers of at most a quarter turn each.
+// The conversion follows appendix B.2.4 of the SVG 2 specification.
+func (p *svgPath) arcTo(x1, y1, rx, ry, rotation float64, largeArc, sweep bool, x2, y2 float64) {
+	if x1 == x2 && y1 == y2 {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		p.lineTo(x2, y2)
+		return
+	}
+
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx2, dy2 := (x1-x2)/2, (y1-y2)/2
+	x1p := cos*dx2 + sin*dy2
+	y1p := -sin*dx2 + cos*dy2
+
+	lambda := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry)
+	if lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	coef := math.Sqrt(math.Max(0, num/den))
+	if largeArc == sweep {
+		coef = -coef
+	}
+	cxp, cyp := coef*rx*y1p/ry, -coef*ry*x1p/rx
+	cx := cos*cxp - sin*cyp + (x1+x2)/2
+	cy := sin*cxp + cos*cyp + (y1+y2)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1p-cxp)/rx, (y1p-cyp)/ry)
+	delta := angle((x1p-cxp)/rx, (y1p-cyp)/ry, (-x1p-cxp)/rx, (-y1p-cyp)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	point := func(a float64) (float64, float64) {
+		return cx + rx*math.Cos(a)*cos - ry*math.Sin(a)*sin,
+			cy + rx*math.Cos(a)*sin + ry*math.Sin(a)*cos
+	}
+	tangent := func(a float64) (float64, float64) {
+		return -rx*math.Sin(a)*cos - ry*math.Cos(a)*sin,
+			-rx*math.Sin(a)*sin + ry*math.Cos(a)*cos
+	}
+
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+	for i := 0; i < n; i++ {
+		a1 := theta + float64(i)*step
+		a2 := a1 + step
+		px1, py1 := point(a1)
+		px2, py2 := point(a2)
+		tx1, ty1 := tangent(a1)
+		tx2, ty2 := tangent(a2)
+		if i == n-1 {
+			px2, py2 = x2, y2 // avoid accumulated rounding at the end
+		}
+		p.curveTo(px1+k*tx1, py1+k*ty1, px2-k*tx2, py2-k*ty2, px2, py2)
+	}
+}
+
+// emit sends the path to the PDF, transformed to page coordinates.
+func (p svgPath) emit(pdf *gofpdf.Fpdf, ctm svgMatrix) {
+	for _, s := range p {
+		switch s.op {
+		case 'M':
+			pdf.MoveTo(ctm.apply(s.pts[0], s.pts[1]))
+		case 'L':
+			pdf.LineTo(ctm.apply(s.pts[0], s.pts[1]))
+		case 'C':
+			x1, y1 := ctm.apply(s.pts[0], s.pts[1])
+			x2, y2 := ctm.apply(s.pts[2], s.pts[3])
+			x, y := ctm.apply(s.pts[4], s.pts[5])
+			pdf.CurveBezierCubicTo(x1, y1, x2, y2, x, y)
+		case 'Z':
+			pdf.ClosePath()
+		}
+	}
+}
+
+func rectPath(x, y, w, h float64, rxAttr, ryAttr string) svgPath {
+	var p svgPath
+	if w <= 0 || h <= 0 {
+		return p
+	}
+
+	rx, rxok := svgLength(rxAttr, 0)
+	ry, ryok := svgLength(ryAttr, 0)
+	if !rxok {
+		rx = ry
+	}
+	if !ryok {
+		ry = rx
+	}
+	rx, ry = math.Min(rx, w/2), math.Min(ry, h/2)
+
+	if rx <= 0 || ry <= 0 {
+		p.moveTo(x, y)
+		p.lineTo(x+w, y)
+		p.lineTo(x+w, y+h)
+		p.lineTo(x, y+h)
+		p.close()
+		return p
+	}
+
+	p.moveTo(x+rx, y)
+	p.lineTo(x+w-rx, y)
+	p.arcTo(x+w-rx, y, rx, ry, 0, false, true, x+w, y+ry)
+	p.lineTo(x+w, y+h-ry)
+	p.arcTo(x+w, y+h-ry, rx, ry, 0, false, true, x+w-rx, y+h)
+	p.lineTo(x+rx, y+h)
+	p.arcTo(x+rx, y+h, rx, ry, 0, false, true, x, y+h-ry)
+	p.lineTo(x, y+ry)
+	p.arcTo(x, y+ry, rx, ry, 0, false, true, x+rx, y)
+	p.close()
+	return p
+}
+
+func ellipsePath(cx, cy, rx, ry float64) svgPath {
+	var p svgPath
+	if rx <= 0 || ry <= 0 {
+		return p
+	}
+	p.moveTo(cx+rx, cy)
+	p.arcTo(cx+rx, cy, rx, ry, 0, false, true, cx, cy+ry)
+	p.arcTo(cx, cy+ry, rx, ry, 0, false, true, cx-rx, cy)
+	p.arcTo(cx-rx, cy, rx, ry, 0, false, true, cx, cy-ry)
+	p.arcTo(cx, cy-ry, rx, ry, 0, false, true, cx+rx, cy)
+	p.close()
+	return p
+}
+
+// parsePathData reads the "d" attribute of a path element. Relative
+// commands are made absolute, and horizontal, vertical, quadratic, smooth
+// and arc commands are converted. Parsing stops at the first error, as the
+// SVG specification requires, keeping what was read up to that point.
+func parsePathData(d string) svgPath {
+	var p svgPath
+	sc := pathScanner{s: d}
+	var cmd byte
+	var x, y, startX, startY float64
+	var ctrlX, ctrlY float64 // reflected control point for S and T
+	var lastCmd byte
+
+	for {
+		sc.skipSeparators()
+		if sc.i >= len(sc.s) {
+			return p
+		}
+		if c := sc.s[sc.i]; (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+			cmd = c
+			sc.i++
+		} else if cmd == 0 {
+			return p
+		}
+
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = x, y
+		}
+
+		nums := func(n int) ([]float64, bool) {
+			v := make([]float64, n)
+			for i := range v {
+				f, ok := sc.number()
+				if !ok {
+					return nil, false
+				}
+				v[i] = f
+			}
+			return v, true
+		}
+
+		switch cmd | 0x20 { // lower case
+		case 'z':
+			p.close()
+			x, y = startX, startY
+			lastCmd = 'z'
+			cmd = 0
+			continue
+
+		case 'm':
+			v, ok := nums(2)
+			if !ok {
+				return p
+			}
+			x, y = v[0]+ox, v[1]+oy
+			startX, startY = x, y
+			p.moveTo(x, y)
+			// subsequent pairs are implicit line-tos
+			if rel {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+
+		case 'l':
+			v, ok := nums(2)
+			if !ok {
+				return p
+			}
+			x, y = v[0]+ox, v[1]+oy
+			p.lineTo(x, y)
+
+		case 'h':
+			v, ok := nums(1)
+			if !ok {
+				return p
+			}
+			x = v[0] + ox
+			p.lineTo(x, y)
+
+		case 'v':
+			v, ok := nums(1)
+			if !ok {
+				return p
+			}
+			y = v[0] + oy
+			p.lineTo(x, y)
+
+		case 'c':
+			v, ok := nums(6)
+			if !ok {
+				return p
+			}
+			p.curveTo(v[0]+ox, v[1]+oy, v[2]+ox, v[3]+oy, v[4]+ox, v[5]+oy)
+			ctrlX, ctrlY = v[2]+ox, v[3]+oy
+			x, y = v[4]+ox, v[5]+oy
+
+		case 's':
+			v, ok := nums(4)
+			if !ok {
+				return p
+			}
+			c1x, c1y := x, y
+			if lastCmd == 'c' || lastCmd == 's' {
+				c1x, c1y = 2*x-ctrlX, 2*y-ctrlY
+			}
+			p.curveTo(c1x, c1y, v[0]+ox, v[1]+oy, v[2]+ox, v[3]+oy)
+			ctrlX, ctrlY = v[0]+ox, v[1]+oy
+			x, y = v[2]+ox, v[3]+oy
+
+		case 'q':
+			v, ok := nums(4)
+			if !ok {
+				return p
+			}
+			qx, qy := v[0]+ox, v[1]+oy
+			ex, ey := v[2]+ox, v[3]+oy
+			p.quadTo(x, y, qx, qy, ex, ey)
+			ctrlX, ctrlY = qx, qy
+			x, y = ex, ey
+
+		case 't':
+			v, ok := nums(2)
+			if !ok {
+				return p
+			}
+			qx, qy := x, y
+			if lastCmd == 'q' || lastCmd == 't' {
+				qx, qy = 2*x-ctrlX, 2*y-ctrlY
+			}
+			ex, ey := v[0]+ox, v[1]+oy
+			p.quadTo(x, y, qx, qy, ex, ey)
+			ctrlX, ctrlY = qx, qy
+			x, y = ex, ey
+
+		case 'a':
+			v, ok := nums(3)
+			if !ok {
+				return p
+			}
+			large, ok1 := sc.flag()
+			sweep, ok2 := sc.flag()
+			end, ok3 := nums(2)
+			if !ok1 || !ok2 || !ok3 {
+				return p
+			}
+			ex, ey := end[0]+ox, end[1]+oy
+			p.arcTo(x, y, v[0], v[1], v[2], large, sweep, ex, ey)
+			x, y = ex, ey
+
+		default:
+			return p
+		}
+
+		lastCmd = cmd | 0x20
+	}
+}
+
+// quadTo adds a quadratic Bézier from (x0, y0) as the equivalent cubic.
+func (p *svgPath) quadTo(x0, y0, qx, qy, x, y float64) {
+	p.curveTo(x0+2*(qx-x0)/3, y0+2*(qy-y0)/3, x+2*(qx-x)/3, y+2*(qy-y)/3, x, y)
+}
+
+// pathScanner reads numbers and flags from path data, where numbers may be
+// separated by whitespace, commas, signs or (after a fraction) a second
+// decimal point, as in "M1.5.5-2e1".
+type pathScanner struct {
+	s string
+	i int
+}
+
+func (sc *pathScanner) skipSeparators() {
+	for sc.i < len(sc.s) {
+		switch sc.s[sc.i] {
+		case ' ', '\t', '\n', '\r', '\f', ',':
+			sc.i++
+		default:
+			return
+		}
+	}
+}
+
+func (sc *pathScanner) number() (float64, bool) {
+	sc.skipSeparators()
+	start := sc.i
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '+' || sc.s[sc.i] == '-') {
+		sc.i++
+	}
+	digits, dot := 0, false
+	for sc.i < len(sc.s) {
+		c := sc.s[sc.i]
+		if c >= '0' && c <= '9' {
+			digits++
+		} else if c == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+		sc.i++
+	}
+	if digits == 0 {
+		sc.i = start
+		return 0, false
+	}
+	if sc.i < len(sc.s) && (sc.s[sc.i] == 'e' || sc.s[sc.i] == 'E') {
+		j := sc.i + 1
+		if j < len(sc.s) && (sc.s[j] == '+' || sc.s[j] == '-') {
+			j++
+		}
+		if j < len(sc.s) && sc.s[j] >= '0' && sc.s[j] <= '9' {
+			for j < len(sc.s) && sc.s[j] >= '0' && sc.s[j] <= '9' {
+				j++
+			}
+			sc.i = j
+		}
+	}
+	f, err := strconv.ParseFloat(sc.s[start:sc.i], 64)
+	return f, err == nil
+}
+
+// flag reads an arc flag, which is a single '0' or '1' that need not be
+// separated from what follows.
+func (sc *pathScanner) flag() (bool, bool) {
+	sc.skipSeparators()
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '0' || sc.s[sc.i] == '1') {
+		sc.i++
+		return sc.s[sc.i-1] == '1', true
+	}
+	return false, false
+}
+
+// parseNumbers reads a list of numbers separated by whitespace and/or
+// commas, as used by viewBox, points and transform arguments.
+func parseNumbers(s string) []float64 {
+	var nums []float64
+	sc := pathScanner{s: s}
+	for {
+		f, ok := sc.number()
+		if !ok {
+			return nums
+		}
+		nums = append(nums, f)
+	}
+}
+
+// svgLength reads a length in user units (i.e. pixels). Percentages are
+// taken relative to ref.
+func svgLength(s string, ref float64) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if strings.HasSuffix(s, "%") {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		return f * ref / 100, err == nil
+	}
+	if strings.HasSuffix(s, "em") {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(s, "em"), 64)
+		return f * 16, err == nil
+	}
+	pts, _, ok := parseLength(s)
+	return pts / 0.75, ok
+}
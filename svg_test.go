@@ -0,0 +1,104 @@
+package mdtopdf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePathData(t *testing.T) {
+	p := parsePathData("M10,20l5-5H30v10h-2.5.5zm1 1 2 2")
+
+	want := []struct {
+		op   byte
+		x, y float64
+	}{
+		{'M', 10, 20}, {'L', 15, 15}, {'L', 30, 15}, {'L', 30, 25},
+		{'L', 27.5, 25}, {'L', 28, 25}, {'Z', 0, 0}, {'M', 11, 21}, {'L', 13, 23},
+	}
+	if len(p) != len(want) {
+		t.Fatalf("got %d segments %v; want %d", len(p), p, len(want))
+	}
+	for i, w := range want {
+		if p[i].op != w.op {
+			t.Errorf("%d: got op %c; want %c", i, p[i].op, w.op)
+		} else if w.op != 'Z' && (p[i].pts[0] != w.x || p[i].pts[1] != w.y) {
+			t.Errorf("%d: got %v; want %v,%v", i, p[i].pts, w.x, w.y)
+		}
+	}
+}
+
+func TestParsePathDataCurvesAndArcs(t *testing.T) {
+	p := parsePathData("M0 0 Q 10 10 20 0 T 40 0 A10 10 0 0160 0")
+
+	last := p[len(p)-1]
+	if last.op != 'C' {
+		t.Fatalf("got %c; want C", last.op)
+	}
+	if x, y := last.pts[4], last.pts[5]; x != 60 || y != 0 {
+		t.Errorf("arc ends at %v,%v; want 60,0", x, y)
+	}
+
+	// a semicircle from (40,0) to (60,0) sweeping clockwise (in SVG's
+	// downward y axis) reaches its top at (50,-10)
+	var top float64
+	for _, s := range p[3:] {
+		top = math.Min(top, s.pts[5])
+	}
+	if math.Abs(top+10) > 1e-9 {
+		t.Errorf("arc reaches y=%v; want -10", top)
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	m := parseTransform("translate(10, 20) scale(2) rotate(90)")
+	x, y := m.apply(1, 0)
+	if math.Abs(x-10) > 1e-9 || math.Abs(y-22) > 1e-9 {
+		t.Errorf("got %v,%v; want 10,22", x, y)
+	}
+}
+
+func TestParseSVG(t *testing.T) {
+	img, err := parseSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100" width="10cm"><rect width="10" height="10"/></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, h := img.extent()
+	if math.Abs(w-283.46) > 0.01 || math.Abs(h-141.73) > 0.01 {
+		t.Errorf("got %vx%v", w, h)
+	}
+
+	_, err = parseSVG([]byte(`<html></html>`))
+	if err == nil {
+		t.Errorf("expected an error for a non-SVG document")
+	}
+}
+
+func TestSVGPaint(t *testing.T) {
+	cases := []struct {
+		in    string
+		want  Color
+		alpha float64
+		ok    bool
+	}{
+		{"red", Color{255, 0, 0}, 1, true},
+		{"#0f0", Color{0, 255, 0}, 1, true},
+		{"#0366d6", Color{3, 102, 214}, 1, true},
+		{"rgb(10, 20, 30)", Color{10, 20, 30}, 1, true},
+		{"rgb(100%,0%,50%)", Color{255, 0, 128}, 1, true},
+		{"rgba(10, 20, 30, 0.5)", Color{10, 20, 30}, 0.5, true},
+		{"currentColor", Color{0, 0, 128}, 1, true},
+		{"url(#gradient) #00f", Color{0, 0, 255}, 1, true},
+		{"url(#gradient)", Color{}, 0, false},
+		{"none", Color{}, 0, false},
+		{"transparent", Color{}, 0, false},
+		{"#00000000", Color{}, 0, false},
+		{"bluish", Color{}, 0, false},
+	}
+	style := svgStyle{color: "navy"}
+	for _, c := range cases {
+		got, alpha, ok := style.paint(c.in)
+		if ok != c.ok || (ok && (got != c.want || alpha != c.alpha)) {
+			t.Errorf("%s: got %v %v %v; want %v %v %v", c.in, got, alpha, ok, c.want, c.alpha, c.ok)
+		}
+	}
+}
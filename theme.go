@@ -0,0 +1,461 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Theme describes the appearance of a document: the style of each kind of
+// text, the page margins, the indentation, the list bullets and the
+// colours. Themes can be written and read as JSON or YAML, and a few are
+// built in; see BuiltinTheme.
+type Theme struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	Normal     Styler `json:"normal" yaml:"normal"`
+	Link       Styler `json:"link" yaml:"link"`
+	Backtick   Styler `json:"backtick" yaml:"backtick"`
+	Blockquote Styler `json:"blockquote" yaml:"blockquote"`
+	H1         Styler `json:"h1" yaml:"h1"`
+	H2         Styler `json:"h2" yaml:"h2"`
+	H3         Styler `json:"h3" yaml:"h3"`
+	H4         Styler `json:"h4" yaml:"h4"`
+	H5         Styler `json:"h5" yaml:"h5"`
+	H6         Styler `json:"h6" yaml:"h6"`
+	THeader    Styler `json:"theader" yaml:"theader"`
+	TBody      Styler `json:"tbody" yaml:"tbody"`
+	Caption    Styler `json:"caption" yaml:"caption"`
+
+	// Margins are those of the page, in points. The bottom margin is
+	// where text breaks onto a new page.
+	Margins Margins `json:"margins" yaml:"margins"`
+
+	// Indent is the indentation of block quotes and lists, in points.
+	// Zero means three ems of normal text.
+	Indent float64 `json:"indent,omitempty" yaml:"indent,omitempty"`
+
+	// QuoteIndent, if not zero, is the indentation of block quotes in
+	// place of Indent.
+	QuoteIndent float64 `json:"quoteIndent,omitempty" yaml:"quoteIndent,omitempty"`
+
+	// Bullets mark the items of unordered lists, one for each level of
+	// nesting.
+	Bullets []string `json:"bullets,omitempty" yaml:"bullets,omitempty,flow"`
+
+	// TitlePage gives the styles of the title page.
+	TitlePage TitleStyles `json:"titlePage" yaml:"titlePage"`
+
+	// BorderColor is the colour of table borders.
+	BorderColor Color `json:"borderColor" yaml:"borderColor"`
+
+	// PlaceholderColor and PlaceholderFill are the border and the inside
+	// of the box drawn in place of a missing image.
+	PlaceholderColor Color `json:"placeholderColor" yaml:"placeholderColor"`
+	PlaceholderFill  Color `json:"placeholderFill" yaml:"placeholderFill"`
+
+	// Background is the colour of the pages; nil leaves them white.
+	Background *Color `json:"background,omitempty" yaml:"background,omitempty"`
+
+	// BackgroundImage is the path or URL of an image stretched over each
+	// page.
+	BackgroundImage string `json:"backgroundImage,omitempty" yaml:"backgroundImage,omitempty"`
+}
+
+// Margins are the page margins, in points.
+type Margins struct {
+	Left   float64 `json:"left" yaml:"left"`
+	Top    float64 `json:"top" yaml:"top"`
+	Right  float64 `json:"right" yaml:"right"`
+	Bottom float64 `json:"bottom" yaml:"bottom"`
+}
+
+// DefaultTheme is the appearance of a new PdfRenderer.
+func DefaultTheme() Theme {
+	return Theme{
+		Name:        "default",
+		Normal:      Styler{Font: sansFont, Style: "", Size: 10, Spacing: 4, TextColor: Black, FillColor: White},
+		Link:        Styler{Font: sansFont, Style: "u", Size: 10, Spacing: 4, TextColor: ColorOf("#0366d6"), FillColor: White},
+		Backtick:    Styler{Font: monoFont, Style: "", Size: 10, Spacing: 4, TextColor: Color{37, 27, 14}, FillColor: Grey(230)},
+		Blockquote:  Styler{Font: sansFont, Style: "i", Size: 10, Spacing: 4, TextColor: Black, FillColor: White},
+		H1:          Styler{Font: sansFont, Style: "b", Size: 18, Spacing: 6, TextColor: Black, FillColor: White},
+		H2:          Styler{Font: sansFont, Style: "b", Size: 16, Spacing: 6, TextColor: Black, FillColor: White},
+		H3:          Styler{Font: sansFont, Style: "b", Size: 14, Spacing: 6, TextColor: Black, FillColor: White},
+		H4:          Styler{Font: sansFont, Style: "b", Size: 12, Spacing: 6, TextColor: Black, FillColor: White},
+		H5:          Styler{Font: sansFont, Style: "b", Size: 10, Spacing: 6, TextColor: Black, FillColor: White},
+		H6:          Styler{Font: sansFont, Style: "b", Size: 9, Spacing: 6, TextColor: Black, FillColor: White},
+		THeader:     Styler{Font: sansFont, Style: "B", Size: 10, Spacing: 4, TextColor: Black, FillColor: Grey(180)},
+		TBody:       Styler{Font: sansFont, Style: "", Size: 10, Spacing: 4, TextColor: Black, FillColor: Grey(240)},
+		Caption:     Styler{Font: sansFont, Style: "i", Size: 9, Spacing: 3, TextColor: Grey(64), FillColor: White},
+		Margins:     Margins{Left: 28.35, Top: 28.35, Right: 28.35, Bottom: 56.7},
+		Bullets:     []string{"-"},
+		BorderColor: Color{128, 0, 0},
+
+		TitlePage:        titleStyles(sansFont, Black, Grey(64), White),
+		PlaceholderColor: Grey(160),
+		PlaceholderFill:  Grey(245),
+	}
+}
+
+// githubTheme resembles the way GitHub shows markdown.
+func githubTheme() Theme {
+	text := ColorOf("#24292e")
+	grey := ColorOf("#6a737d")
+	h := func(size float64) Styler {
+		return Styler{Font: sansFont, Style: "b", Size: size, Spacing: 8, TextColor: text, FillColor: White}
+	}
+	return Theme{
+		Name:        "github",
+		Normal:      Styler{Font: sansFont, Size: 11, Spacing: 6, TextColor: text, FillColor: White},
+		Link:        Styler{Font: sansFont, Size: 11, Spacing: 6, TextColor: ColorOf("#0366d6"), FillColor: White},
+		Backtick:    Styler{Font: monoFont, Size: 10, Spacing: 6, TextColor: text, FillColor: ColorOf("#f6f8fa")},
+		Blockquote:  Styler{Font: sansFont, Size: 11, Spacing: 6, TextColor: grey, FillColor: White},
+		H1:          h(22),
+		H2:          h(18),
+		H3:          h(15),
+		H4:          h(12),
+		H5:          h(11),
+		H6:          Styler{Font: sansFont, Style: "b", Size: 10, Spacing: 8, TextColor: grey, FillColor: White},
+		THeader:     Styler{Font: sansFont, Style: "b", Size: 11, Spacing: 6, TextColor: text, FillColor: White},
+		TBody:       Styler{Font: sansFont, Size: 11, Spacing: 6, TextColor: text, FillColor: ColorOf("#f6f8fa")},
+		Caption:     Styler{Font: sansFont, Size: 9, Spacing: 4, TextColor: grey, FillColor: White},
+		Margins:     Margins{Left: 54, Top: 54, Right: 54, Bottom: 54},
+		Indent:      24,
+		Bullets:     []string{"*", "o", "-"},
+		BorderColor: ColorOf("#dfe2e5"),
+
+		TitlePage:        titleStyles(sansFont, text, grey, White),
+		PlaceholderColor: ColorOf("#dfe2e5"),
+		PlaceholderFill:  ColorOf("#f6f8fa"),
+	}
+}
+
+// academicTheme is a serif theme with justified text and wide margins.
+func academicTheme() Theme {
+	s := func(style string, size, spacing float64) Styler {
+		return Styler{Font: serifFont, Style: style, Size: size, Spacing: spacing, TextColor: Black, FillColor: White}
+	}
+	normal := s("", 11, 4)
+	normal.Align = AlignJustify
+	h1 := s("b", 16, 8)
+	h1.Align = AlignCenter
+	quote := s("", 10, 4)
+	quote.Align = AlignJustify
+	return Theme{
+		Name:        "academic",
+		Normal:      normal,
+		Link:        s("", 11, 4),
+		Backtick:    Styler{Font: monoFont, Size: 10, Spacing: 4, TextColor: Black, FillColor: White},
+		Blockquote:  quote,
+		H1:          h1,
+		H2:          s("b", 13, 6),
+		H3:          s("bi", 12, 6),
+		H4:          s("i", 11, 4),
+		H5:          s("i", 11, 4),
+		H6:          s("i", 10, 4),
+		THeader:     s("b", 10, 4),
+		TBody:       s("", 10, 4),
+		Caption:     s("i", 10, 4),
+		Margins:     Margins{Left: 72, Top: 72, Right: 72, Bottom: 72},
+		Indent:      22,
+		Bullets:     []string{"-", "o", "*"},
+		BorderColor: Black,
+
+		TitlePage:        titleStyles(serifFont, Black, Black, White),
+		PlaceholderColor: Grey(160),
+		PlaceholderFill:  White,
+	}
+}
+
+// compactTheme fits more onto each page.
+func compactTheme() Theme {
+	t := DefaultTheme()
+	t.Name = "compact"
+	for _, s := range []*Styler{&t.Normal, &t.Link, &t.Backtick, &t.Blockquote, &t.THeader, &t.TBody} {
+		s.Size, s.Spacing = 9, 2
+	}
+	for i, s := range []*Styler{&t.H1, &t.H2, &t.H3, &t.H4, &t.H5, &t.H6} {
+		s.Size, s.Spacing = 14-float64(i), 3
+	}
+	t.Caption.Size, t.Caption.Spacing = 8, 2
+	t.Margins = Margins{Left: 20, Top: 20, Right: 20, Bottom: 36}
+	t.Indent = 16
+	return t
+}
+
+// darkTheme is the github theme as light text on dark pages, for reading
+// on screen.
+func darkTheme() Theme {
+	t := githubTheme()
+	t.Name = "dark"
+	page := ColorOf("#0d1117")
+	text := ColorOf("#c9d1d9")
+	grey := ColorOf("#8b949e")
+	for _, s := range []*Styler{&t.Normal, &t.H1, &t.H2, &t.H3, &t.H4, &t.H5} {
+		s.TextColor, s.FillColor = text, page
+	}
+	for _, s := range []*Styler{&t.H6, &t.Blockquote, &t.Caption} {
+		s.TextColor, s.FillColor = grey, page
+	}
+	t.Link.TextColor, t.Link.FillColor = ColorOf("#58a6ff"), page
+	t.Backtick.TextColor, t.Backtick.FillColor = text, ColorOf("#262c36")
+	t.THeader.TextColor, t.THeader.FillColor = ColorOf("#f0f6fc"), ColorOf("#21262d")
+	t.TBody.TextColor, t.TBody.FillColor = text, ColorOf("#161b22")
+	t.TitlePage = titleStyles(sansFont, text, grey, page)
+	t.BorderColor = ColorOf("#30363d")
+	t.PlaceholderColor, t.PlaceholderFill = grey, ColorOf("#161b22")
+	t.Background = &page
+	return t
+}
+
+// highContrastTheme is white text on black pages, with yellow links and
+// cyan code, and larger type.
+func highContrastTheme() Theme {
+	t := DefaultTheme()
+	t.Name = "high-contrast"
+	page := Black
+	for _, s := range []*Styler{&t.Normal, &t.Link, &t.Backtick, &t.Blockquote,
+		&t.H1, &t.H2, &t.H3, &t.H4, &t.H5, &t.H6, &t.THeader, &t.TBody, &t.Caption} {
+		s.Size += 2
+		s.TextColor, s.FillColor = White, page
+	}
+	t.Link.TextColor = ColorOf("#ffff00")
+	t.Backtick.TextColor = ColorOf("#00ffff")
+	t.THeader.TextColor, t.THeader.FillColor = Black, White
+	t.TitlePage = titleStyles(sansFont, White, White, page)
+	t.BorderColor = White
+	t.PlaceholderColor, t.PlaceholderFill = White, page
+	t.Background = &page
+	return t
+}
+
+var builtinThemes = map[string]func() Theme{
+	"default":       DefaultTheme,
+	"github":        githubTheme,
+	"academic":      academicTheme,
+	"compact":       compactTheme,
+	"dark":          darkTheme,
+	"high-contrast": highContrastTheme,
+}
+
+// BuiltinTheme gives one of the built-in themes: "default", "github",
+// "academic", "compact", "dark" or "high-contrast".
+func BuiltinTheme(name string) (Theme, bool) {
+	fn, ok := builtinThemes[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, false
+	}
+	return fn(), true
+}
+
+// BuiltinThemes lists the names of the built-in themes.
+func BuiltinThemes() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadTheme reads a theme in JSON or YAML. Anything the theme leaves out
+// is taken from the default theme.
+func ReadTheme(rd io.Reader) (Theme, error) {
+	data, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	t := DefaultTheme()
+	t.Name = ""
+	t.Bullets = nil
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, &t)
+	} else {
+		err = yaml.Unmarshal(data, &t)
+	}
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme: %w", err)
+	}
+	if len(t.Bullets) == 0 {
+		t.Bullets = DefaultTheme().Bullets
+	}
+	return t, nil
+}
+
+// LoadTheme reads a theme from a JSON or YAML file.
+func LoadTheme(path string) (Theme, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	t, err := ReadTheme(bytes.NewReader(data))
+	if err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// WriteJSON writes the theme as indented JSON.
+func (t Theme) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteYAML writes the theme as YAML.
+func (t Theme) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Save writes the theme to a file, as YAML if its name ends in ".yaml" or
+// ".yml" and otherwise as JSON.
+func (t Theme) Save(path string) error {
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = t.WriteYAML(&buf)
+	default:
+		err = t.WriteJSON(&buf)
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Theme gives the renderer's current appearance, e.g. for saving.
+func (r *PdfRenderer) Theme() Theme {
+	_, bottom := r.Pdf.GetAutoPageBreak()
+	return Theme{
+		Normal:          r.Normal,
+		Link:            r.Link,
+		Backtick:        r.Backtick,
+		Blockquote:      r.Blockquote,
+		H1:              r.H1,
+		H2:              r.H2,
+		H3:              r.H3,
+		H4:              r.H4,
+		H5:              r.H5,
+		H6:              r.H6,
+		THeader:         r.THeader,
+		TBody:           r.TBody,
+		Caption:         r.Caption,
+		Margins:         Margins{Left: r.mleft, Top: r.mtop, Right: r.mright, Bottom: bottom},
+		Indent:          r.IndentValue,
+		QuoteIndent:     r.QuoteIndent,
+		Bullets:         append([]string(nil), r.Bullets...),
+		BorderColor:     r.BorderColor,
+		Background:      copyColor(r.Background),
+		BackgroundImage: r.BackgroundImage,
+
+		TitlePage: TitleStyles{
+			Title:    r.TitleLayout.Title,
+			Subtitle: r.TitleLayout.Subtitle,
+			Authors:  r.TitleLayout.Authors,
+			Date:     r.TitleLayout.Date,
+			Version:  r.TitleLayout.Version,
+		},
+		PlaceholderColor: r.PlaceholderColor,
+		PlaceholderFill:  r.PlaceholderFill,
+	}
+}
+
+func copyColor(c *Color) *Color {
+	if c == nil {
+		return nil
+	}
+	copied := *c
+	return &copied
+}
+
+// SetTheme changes the renderer's appearance. It should be called before
+// the document is rendered.
+func (r *PdfRenderer) SetTheme(t Theme) {
+	r.applyStyles(t)
+
+	if t.Margins != (Margins{}) {
+		r.setMargins(t.Margins)
+	}
+
+	r.setStyler(r.Normal)
+	r.em = r.Pdf.GetStringWidth("m")
+	r.IndentValue = t.Indent
+	if r.IndentValue == 0 {
+		r.IndentValue = 3 * r.em
+	}
+	r.QuoteIndent = t.QuoteIndent
+}
+
+// setMargins sets the page margins, in points.
+func (r *PdfRenderer) setMargins(m Margins) {
+	if r.Pdf.PageNo() <= 1 && r.Pdf.GetY() <= r.mtop {
+		// nothing has been written yet
+		r.Pdf.SetXY(m.Left, m.Top)
+	}
+	r.Pdf.SetMargins(m.Left, m.Top, m.Right)
+	r.Pdf.SetAutoPageBreak(true, m.Bottom)
+	r.mleft, r.mtop, r.mright, r.mbottom = m.Left, m.Top, m.Right, m.Bottom
+	r.cs.stack[0].leftMargin = m.Left
+}
+
+// UseTheme sets a built-in theme by name or else loads a theme file. A
+// relative path is taken from BaseDir.
+func (r *PdfRenderer) UseTheme(name string) error {
+	t, err := r.findTheme(name)
+	if err != nil {
+		return err
+	}
+	r.SetTheme(t)
+	return nil
+}
+
+// findTheme gives the built-in theme of that name, or else loads the
+// theme file.
+func (r *PdfRenderer) findTheme(name string) (Theme, error) {
+	if t, ok := BuiltinTheme(name); ok {
+		return t, nil
+	}
+	path := name
+	if r.BaseDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(r.BaseDir, path)
+	}
+	return LoadTheme(path)
+}
+
+// applyStyles copies the text styles, including those of the title page,
+// and the bullets, colours and background of a theme.
+func (r *PdfRenderer) applyStyles(t Theme) {
+	r.Normal, r.Link, r.Backtick, r.Blockquote = t.Normal, t.Link, t.Backtick, t.Blockquote
+	r.H1, r.H2, r.H3, r.H4, r.H5, r.H6 = t.H1, t.H2, t.H3, t.H4, t.H5, t.H6
+	r.THeader, r.TBody, r.Caption = t.THeader, t.TBody, t.Caption
+	r.Bullets = append([]string(nil), t.Bullets...)
+	tl := &r.TitleLayout
+	tl.Title, tl.Subtitle, tl.Authors = t.TitlePage.Title, t.TitlePage.Subtitle, t.TitlePage.Authors
+	tl.Date, tl.Version = t.TitlePage.Date, t.TitlePage.Version
+	r.BorderColor = t.BorderColor
+	r.PlaceholderColor, r.PlaceholderFill = t.PlaceholderColor, t.PlaceholderFill
+	r.Background, r.BackgroundImage = copyColor(t.Background), t.BackgroundImage
+}
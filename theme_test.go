@@ -0,0 +1,124 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestThemeRoundTrip(t *testing.T) {
+	for _, name := range BuiltinThemes() {
+		theme, ok := BuiltinTheme(name)
+		if !ok {
+			t.Fatalf("%s: not found", name)
+		}
+
+		var js, ym bytes.Buffer
+		if err := theme.WriteJSON(&js); err != nil {
+			t.Fatal(err)
+		}
+		if err := theme.WriteYAML(&ym); err != nil {
+			t.Fatal(err)
+		}
+
+		for format, buf := range map[string]*bytes.Buffer{"json": &js, "yaml": &ym} {
+			got, err := ReadTheme(buf)
+			if err != nil {
+				t.Fatalf("%s %s: %v", name, format, err)
+			}
+			if !reflect.DeepEqual(got, theme) {
+				t.Errorf("%s %s: got\n%+v\nwant\n%+v", name, format, got, theme)
+			}
+		}
+	}
+}
+
+func TestReadThemeDefaults(t *testing.T) {
+	yml := `
+name: partial
+h1:
+  size: 24
+  textColor: "#336699"
+margins: {left: 72, top: 72, right: 72, bottom: 72}
+`
+	theme, err := ReadTheme(strings.NewReader(yml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := DefaultTheme()
+	if theme.H1.Size != 24 || theme.H1.TextColor != (Color{0x33, 0x66, 0x99}) {
+		t.Errorf("got H1 %+v", theme.H1)
+	}
+	if theme.H1.Font != def.H1.Font || theme.H1.Style != def.H1.Style {
+		t.Errorf("got H1 %+v; want the default font", theme.H1)
+	}
+	if !reflect.DeepEqual(theme.Normal, def.Normal) || !reflect.DeepEqual(theme.Bullets, def.Bullets) {
+		t.Errorf("got %+v; want the default for the rest", theme)
+	}
+
+	_, err = ReadTheme(strings.NewReader(`{"normal": {"textColor": "blue-ish"}}`))
+	if err == nil {
+		t.Error("expected an error for a bad colour")
+	}
+}
+
+func TestBuiltinTheme(t *testing.T) {
+	if _, ok := BuiltinTheme("Academic"); !ok {
+		t.Error("academic theme not found")
+	}
+	if _, ok := BuiltinTheme("nonesuch"); ok {
+		t.Error("found a theme that doesn't exist")
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	if !reflect.DeepEqual(r.Theme().Normal, DefaultTheme().Normal) {
+		t.Errorf("got %+v; want the default theme", r.Theme())
+	}
+
+	theme, _ := BuiltinTheme("academic")
+	r.SetTheme(theme)
+
+	lm, tm, rm, _ := r.Pdf.GetMargins()
+	_, bm := r.Pdf.GetAutoPageBreak()
+	if got := (Margins{lm, tm, rm, bm}); got != theme.Margins {
+		t.Errorf("got margins %+v; want %+v", got, theme.Margins)
+	}
+	if x, y := r.Pdf.GetXY(); x != theme.Margins.Left || y != theme.Margins.Top {
+		t.Errorf("got position %.1f,%.1f", x, y)
+	}
+	if r.Normal != theme.Normal || r.IndentValue != theme.Indent {
+		t.Errorf("got %+v", r.Theme())
+	}
+}
+
+func TestUseThemeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	theme, _ := BuiltinTheme("compact")
+	theme.Bullets = []string{"*", "+"}
+	if err := theme.Save(filepath.Join(dir, "compact.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewPdfRenderer("", "", "")
+	r.BaseDir = dir
+	if err := r.UseTheme("compact.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Bullets, theme.Bullets) {
+		t.Errorf("got bullets %v", r.Bullets)
+	}
+	if err := r.UseTheme("missing.json"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
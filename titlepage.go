@@ -0,0 +1,203 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"strings"
+)
+
+// Metadata describes the document. It sets the properties of the PDF file
+// and supplies the content of the title page.
+type Metadata struct {
+	Title    string
+	Subtitle string
+	Authors  []string
+	Date     string
+	Version  string
+	Subject  string
+	Keywords []string
+
+	// Logo is the path or URL of an image for the title page, resolved
+	// in the same way as images in the markdown.
+	Logo string
+}
+
+// Title page elements, for TitlePageLayout.Elements.
+const (
+	TitleLogo     = "logo"
+	TitleTitle    = "title"
+	TitleSubtitle = "subtitle"
+	TitleAuthors  = "authors"
+	TitleDate     = "date"
+	TitleVersion  = "version"
+)
+
+// TitleStyles are the styles of the text of the title page, as they are
+// given by a theme.
+type TitleStyles struct {
+	Title    Styler `json:"title" yaml:"title"`
+	Subtitle Styler `json:"subtitle" yaml:"subtitle"`
+	Authors  Styler `json:"authors" yaml:"authors"`
+	Date     Styler `json:"date" yaml:"date"`
+	Version  Styler `json:"version" yaml:"version"`
+}
+
+// titleStyles gives the title page styles in a font, with the title and
+// authors in one colour and the rest in a quieter one.
+func titleStyles(font string, text, muted, page Color) TitleStyles {
+	return TitleStyles{
+		Title:    Styler{Font: font, Style: "b", Size: 28, Spacing: 12, TextColor: text, FillColor: page},
+		Subtitle: Styler{Font: font, Style: "", Size: 18, Spacing: 24, TextColor: muted, FillColor: page},
+		Authors:  Styler{Font: font, Style: "", Size: 14, Spacing: 8, TextColor: text, FillColor: page},
+		Date:     Styler{Font: font, Style: "", Size: 12, Spacing: 6, TextColor: muted, FillColor: page},
+		Version:  Styler{Font: font, Style: "i", Size: 12, Spacing: 6, TextColor: muted, FillColor: page},
+	}
+}
+
+// TitlePageLayout controls the appearance of the title page. Its styles
+// are set by the theme.
+type TitlePageLayout struct {
+	Title, Subtitle, Authors, Date, Version Styler
+
+	// Align is the horizontal alignment of every element: AlignLeft,
+	// AlignCenter or AlignRight.
+	Align string
+
+	// Top is the position of the first element, as a fraction of the
+	// height of the page.
+	Top float64
+
+	// LogoWidth is the width of the logo in points. Its height keeps the
+	// image's aspect ratio.
+	LogoWidth float64
+
+	// Elements lists what appears on the page, from top to bottom. Blank
+	// metadata are left out.
+	Elements []string
+}
+
+// defaultTitlePageLayout centres the elements a third of the way down.
+func defaultTitlePageLayout() TitlePageLayout {
+	ts := DefaultTheme().TitlePage
+	return TitlePageLayout{
+		Title:     ts.Title,
+		Subtitle:  ts.Subtitle,
+		Authors:   ts.Authors,
+		Date:      ts.Date,
+		Version:   ts.Version,
+		Align:     AlignCenter,
+		Top:       0.3,
+		LogoWidth: 144,
+		Elements:  []string{TitleLogo, TitleTitle, TitleSubtitle, TitleAuthors, TitleDate, TitleVersion},
+	}
+}
+
+// setProperties copies the metadata into the PDF's document properties.
+func (r *PdfRenderer) setProperties() {
+	m := r.Metadata
+	if m.Title != "" {
+		r.Pdf.SetTitle(m.Title, true)
+	}
+	if m.Subject != "" {
+		r.Pdf.SetSubject(m.Subject, true)
+	}
+	if len(m.Authors) > 0 {
+		r.Pdf.SetAuthor(strings.Join(m.Authors, ", "), true)
+	}
+	if len(m.Keywords) > 0 {
+		r.Pdf.SetKeywords(strings.Join(m.Keywords, ", "), true)
+	}
+}
+
+// renderTitlePage fills the first page with the metadata, laid out as
+// TitleLayout says, and starts the body on a new page.
+func (r *PdfRenderer) renderTitlePage() {
+	if !r.TitlePage || r.Metadata.Title == "" {
+		return
+	}
+
+	r.tracer("Title page", r.Metadata.Title)
+	layout := r.TitleLayout
+	_, pageH := r.Pdf.GetPageSize()
+	r.Pdf.SetY(pageH * layout.Top)
+
+	for _, e := range layout.Elements {
+		switch e {
+		case TitleLogo:
+			r.titleLogo(layout)
+		case TitleTitle:
+			r.titleText(layout.Title, layout.Align, r.Metadata.Title)
+		case TitleSubtitle:
+			r.titleText(layout.Subtitle, layout.Align, r.Metadata.Subtitle)
+		case TitleAuthors:
+			for _, a := range r.Metadata.Authors {
+				r.titleText(layout.Authors, layout.Align, a)
+			}
+		case TitleDate:
+			r.titleText(layout.Date, layout.Align, r.Metadata.Date)
+		case TitleVersion:
+			r.titleText(layout.Version, layout.Align, r.Metadata.Version)
+		}
+	}
+
+	r.addPage()
+	r.setStyler(r.Normal)
+}
+
+func (r *PdfRenderer) titleText(s Styler, align, text string) {
+	if text == "" {
+		return
+	}
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	r.setStyler(s)
+	r.Pdf.SetX(lm)
+	r.Pdf.MultiCell(pageW-lm-rm, s.Size+s.Spacing, r.visual(text), "", alignOf(align), false)
+}
+
+// titleLogo draws the logo at LogoWidth, or smaller if the image is
+// narrower, followed by a gap.
+func (r *PdfRenderer) titleLogo(layout TitlePageLayout) {
+	dest := r.Metadata.Logo
+	if dest == "" {
+		return
+	}
+	img, err := r.loadImage(dest)
+	if err != nil {
+		r.tracer("Title page (logo error)", err.Error())
+		warning := ImageWarning{Destination: dest, Err: err}
+		r.imageWarnings = append(r.imageWarnings, warning)
+		if r.MissingImage == ImageFatal {
+			r.err = warning
+		}
+		return
+	}
+
+	w, h := img.extent()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	if layout.LogoWidth > 0 && w > layout.LogoWidth {
+		w, h = layout.LogoWidth, h*layout.LogoWidth/w
+	}
+
+	lm, _, rm, _ := r.Pdf.GetMargins()
+	pageW, _ := r.Pdf.GetPageSize()
+	x := lm
+	switch alignOf(layout.Align) {
+	case AlignCenter:
+		x = (lm + pageW - rm - w) / 2
+	case AlignRight:
+		x = pageW - rm - w
+	}
+	y := r.Pdf.GetY()
+	img.draw(r, x, y, w, h)
+	r.Pdf.SetY(y + h + layout.Title.Size)
+}
@@ -0,0 +1,102 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestTitlePageProperties(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	r.Metadata = Metadata{
+		Title:    "The Title",
+		Subject:  "The Subject",
+		Authors:  []string{"A. Writer", "B. Writer"},
+		Keywords: []string{"pdf", "markdown"},
+	}
+
+	var buf bytes.Buffer
+	if err := r.Process([]byte("Some text.\n")).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if r.Pdf.PageNo() != 1 {
+		t.Errorf("got %d pages; want 1 without a title page", r.Pdf.PageNo())
+	}
+	for _, key := range []string{"/Title", "/Subject", "/Author", "/Keywords"} {
+		if !bytes.Contains(buf.Bytes(), []byte(key)) {
+			t.Errorf("missing %s property", key)
+		}
+	}
+}
+
+func TestTitlePageLayout(t *testing.T) {
+	cases := []struct {
+		title string
+		logo  string
+		pages int
+		warn  int
+	}{
+		{"", "", 1, 0}, // no title, no title page
+		{"The Title", "", 2, 0},
+		{"The Title", "image/fpdf.png", 2, 0},
+		{"The Title", "image/missing.png", 2, 1},
+	}
+
+	for _, c := range cases {
+		r := NewPdfRenderer("", "", "")
+		r.TitlePage = true
+		r.Metadata = Metadata{Title: c.title, Authors: []string{"A. Writer"}, Logo: c.logo}
+		r.TitleLayout.Align = AlignLeft
+		r.TitleLayout.Elements = []string{TitleTitle, TitleLogo, TitleAuthors}
+
+		if err := r.Process([]byte("Some text.\n")).Output(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if r.Pdf.PageNo() != c.pages {
+			t.Errorf("%+v: got %d pages", c, r.Pdf.PageNo())
+		}
+		if len(r.ImageWarnings()) != c.warn {
+			t.Errorf("%+v: got warnings %v", c, r.ImageWarnings())
+		}
+	}
+}
+
+func TestTitlePageTheme(t *testing.T) {
+	r, err := New(WithThemeName("dark"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Pdf.SetCompression(false)
+	r.TitlePage = true
+	r.Metadata = Metadata{Title: "Dark Title", Subtitle: "Dark Subtitle"}
+
+	var buf bytes.Buffer
+	if err := r.Process([]byte("![Gone](image/missing.png)\n")).Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+
+	// each piece of text is drawn in the colour just before it
+	colourOf := func(text string) string {
+		i := bytes.Index(pdf, []byte("("+text))
+		if i < 0 {
+			t.Fatalf("%q wasn't drawn", text)
+		}
+		j := bytes.LastIndex(pdf[:i], []byte(" rg"))
+		return string(pdf[bytes.LastIndexByte(pdf[:j], 'q')+2 : j])
+	}
+	if got := colourOf("Dark Title"); got != "0.788 0.820 0.851" {
+		t.Errorf("title colour %q; want the theme's text colour", got)
+	}
+	if got := colourOf("Dark Subtitle"); got != "0.545 0.580 0.620" {
+		t.Errorf("subtitle colour %q; want the theme's grey", got)
+	}
+
+	// the placeholder for the missing image is a dark box with a grey border
+	for _, op := range []string{"0.545 0.580 0.620 RG", "0.086 0.106 0.133 rg"} {
+		if !bytes.Contains(pdf, []byte(op)) {
+			t.Errorf("no %q for the placeholder", op)
+		}
+	}
+}
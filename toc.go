@@ -0,0 +1,92 @@
+/*
+ * Markdown to PDF Converter
+ * Available at http://github.com/rickb777/mdtopdf
+ *
+ * Copyright © 2018 Cecil New <cecil.new@gmail.com>.
+ * Distributed under the MIT License.
+ * See README.md for details.
+ */
+
+package mdtopdf
+
+import (
+	"fmt"
+	"strconv"
+
+	bf "github.com/russross/blackfriday/v2"
+)
+
+// The table of contents is written before the body, when the page numbers
+// of the headings are not yet known. Each entry's page number is written
+// as an alias that gofpdf replaces with the real number once the heading
+// has been placed.
+
+// tocEntry is a heading listed in the table of contents.
+type tocEntry struct {
+	text  string
+	level int
+	link  int
+	alias string
+}
+
+// renderTableOfContents lists the headings of the document, down to
+// TableOfContentsDepth, on pages of their own before the body.
+func (r *PdfRenderer) renderTableOfContents(ast *bf.Node) {
+	if !r.TableOfContents || ast == nil {
+		return
+	}
+
+	var entries []tocEntry
+	ast.Walk(func(node *bf.Node, entering bool) bf.WalkStatus {
+		if entering && node.Type == bf.Heading && node.HeadingData.Level <= r.TableOfContentsDepth {
+			alias := fmt.Sprintf("{toc:%d}", len(entries)+1)
+			entries = append(entries, tocEntry{
+				text:  plainText(node),
+				level: node.HeadingData.Level,
+				link:  r.anchorLink(headingAnchor(node)),
+				alias: alias,
+			})
+			if r.tocAliases == nil {
+				r.tocAliases = make(map[*bf.Node]string)
+			}
+			r.tocAliases[node] = alias
+			return bf.SkipChildren
+		}
+		return bf.GoToNext
+	})
+	if len(entries) == 0 {
+		return
+	}
+
+	r.tracer("Table of contents", fmt.Sprintf("%d entries", len(entries)))
+	_, tm, _, _ := r.Pdf.GetMargins()
+	if r.Pdf.GetY() > tm {
+		r.addPage()
+	}
+
+	r.setStyler(r.H1)
+	r.Pdf.CellFormat(0, r.H1.Size+r.H1.Spacing, r.TableOfContentsTitle, "", 1, "L", false, 0, "")
+	r.Pdf.Ln(r.H1.Spacing)
+
+	r.setStyler(r.Normal)
+	lh := r.Normal.Size + r.Normal.Spacing
+	pageW, _ := r.Pdf.GetPageSize()
+	numW := 3 * r.em
+	for _, e := range entries {
+		indent := float64(e.level-1) * r.IndentValue
+		textW := pageW - r.mleft - r.mright - numW - indent
+		r.Pdf.SetX(r.mleft + indent)
+		r.Pdf.CellFormat(textW, lh, r.visual(e.text), "", 0, "L", false, e.link, "")
+		// left-aligned, as the alias is wider than the number replacing it
+		r.Pdf.CellFormat(numW, lh, e.alias, "", 1, "L", false, e.link, "")
+	}
+
+	r.addPage()
+}
+
+// tocHeading records the page of a heading listed in the table of contents.
+func (r *PdfRenderer) tocHeading(node *bf.Node) {
+	if alias, ok := r.tocAliases[node]; ok {
+		r.Pdf.RegisterAlias(alias, strconv.Itoa(r.Pdf.PageNo()))
+	}
+}
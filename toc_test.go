@@ -0,0 +1,31 @@
+package mdtopdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTableOfContents(t *testing.T) {
+	r := NewPdfRenderer("", "", "")
+	r.Pdf.SetCompression(false)
+	r.TableOfContents = true
+	r.TableOfContentsDepth = 2
+
+	var buf bytes.Buffer
+	err := r.Process([]byte("# One\n\n## Two\n\n### Three\n\n\\pagebreak\n\n# Four\n")).Output(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.tocAliases) != 3 {
+		t.Errorf("got %d entries; want 3 down to level 2", len(r.tocAliases))
+	}
+
+	if bytes.Contains(buf.Bytes(), []byte("{toc:")) {
+		t.Errorf("page number aliases were not replaced")
+	}
+	for _, s := range []string{"(Contents)Tj", "(One)Tj", "(2)Tj", "(3)Tj"} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("missing %s", s)
+		}
+	}
+}